@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"log/slog"
+	"os"
+	"url-shortener/internal/api/routes"
+	"url-shortener/internal/config"
+	"url-shortener/internal/importer"
+	"url-shortener/internal/lib/alias"
+	"url-shortener/internal/lib/logger"
+)
+
+const importUsage = "usage: url-shortener import -format bitly|yourls|shlink -user-id ID [-report FILE] EXPORT_FILE"
+
+// runImport handles `url-shortener import`, which loads another shortener's
+// export into the links of one user. Skipped and renamed entries are
+// reported as CSV to -report, or to stdout.
+func runImport(cfg *config.Config, log *logger.Logger, args []string) {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	format := flags.String("format", "", "export format")
+	userId := flags.Int64("user-id", 0, "owner of the imported links")
+	reportPath := flags.String("report", "", "report file")
+
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 || *format == "" || *userId <= 0 {
+		log.Fatal(importUsage)
+	}
+
+	if cfg.Storage.Driver == config.StorageDriverMemory {
+		log.Fatal("memory storage cannot be imported into")
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		log.Fatal("failed to open export", slog.String("error", err.Error()))
+	}
+	defer file.Close()
+
+	entries, err := importer.Parse(file, *format)
+	if err != nil {
+		log.Fatal("failed to parse export", slog.String("error", err.Error()))
+	}
+
+	report := os.Stdout
+	if *reportPath != "" {
+		report, err = os.Create(*reportPath)
+		if err != nil {
+			log.Fatal("failed to create report", slog.String("error", err.Error()))
+		}
+		defer report.Close()
+	}
+
+	storage, err := setupStorage(cfg)
+	if err != nil {
+		log.Fatal("failed to init storage", slog.String("error", err.Error()))
+	}
+	defer storage.Close()
+
+	im := importer.New(storage, alias.NewGenerator(cfg.Alias), routes.NewPolicy(cfg), cfg.Alias.Attempts)
+
+	results, importErr := im.Import(entries, *userId)
+
+	var saved, renamed, skipped int
+	for _, res := range results {
+		switch {
+		case res.Skipped:
+			skipped++
+		case res.NewAlias != "":
+			renamed++
+			saved++
+		default:
+			saved++
+		}
+	}
+
+	if err := importer.WriteReport(report, results); err != nil {
+		log.Error("failed to write report", slog.String("error", err.Error()))
+	}
+
+	log.Info("import finished",
+		slog.Int("entries", len(entries)),
+		slog.Int("saved", saved),
+		slog.Int("renamed", renamed),
+		slog.Int("skipped", skipped),
+	)
+
+	if importErr != nil {
+		log.Fatal("import stopped", slog.String("error", importErr.Error()))
+	}
+}
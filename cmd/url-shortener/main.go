@@ -1,11 +1,24 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
 	"url-shortener/internal/api/routes"
 	"url-shortener/internal/config"
+	"url-shortener/internal/lib/analytics"
 	"url-shortener/internal/lib/logger"
+	"url-shortener/internal/lib/metrics"
+	"url-shortener/internal/lib/sweeper"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/instrumented"
+	"url-shortener/internal/storage/memory"
+	"url-shortener/internal/storage/postgres"
 	"url-shortener/internal/storage/sqlite"
 )
 
@@ -14,15 +27,46 @@ func main() {
 
 	log := logger.Setup(cfg.Env)
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			runMigrate(cfg, log, os.Args[2:])
+		case "import":
+			runImport(cfg, log, os.Args[2:])
+		default:
+			log.Fatal("unknown command", slog.String("command", os.Args[1]))
+		}
+		return
+	}
+
 	log.Info("starting server", slog.String("env", cfg.Env))
 	log.Debug("debug logging enabled")
 
-	storage, err := sqlite.New(cfg.StoragePath)
+	storage, err := setupStorage(cfg)
 	if err != nil {
 		log.Fatal("failed to init storage", slog.String("error", err.Error()))
 	}
 
-	router := routes.Setup(log, storage)
+	log.Info("storage initialized", slog.String("driver", cfg.Storage.Driver))
+
+	m := metrics.New()
+	storage = instrumented.New(storage, m.StorageDuration)
+
+	sessionSweeper := sweeper.New(log, "sessions", cfg.Session.SweepInterval, storage.DeleteExpiredSessions)
+	sessionSweeper.Start()
+
+	urlSweeper := sweeper.New(log, "expired_urls", cfg.URLExpiry.SweepInterval, func() (int64, error) {
+		return storage.DeleteExpiredURLs(time.Now().Add(-cfg.URLExpiry.Retention))
+	})
+	urlSweeper.Start()
+
+	clicks := analytics.New(log, storage, cfg.Analytics)
+	clicks.Start()
+	m.RegisterClickQueue(clicks)
+
+	var shuttingDown atomic.Bool
+
+	router := routes.Setup(log, storage, clicks, &shuttingDown, m, cfg)
 
 	log.Info("starting server", slog.String("address", cfg.Address))
 
@@ -34,8 +78,99 @@ func main() {
 		IdleTimeout:  cfg.HTTPServer.IdleTimeout,
 	}
 
-	err = server.ListenAndServe()
-	if err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 2)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	var adminServer *http.Server
+	if cfg.HTTPServer.AdminAddress != "" {
+		log.Info("starting admin server", slog.String("address", cfg.HTTPServer.AdminAddress))
+
+		adminServer = &http.Server{
+			Addr:         cfg.HTTPServer.AdminAddress,
+			Handler:      routes.SetupAdmin(m),
+			ReadTimeout:  cfg.HTTPServer.Timeout,
+			WriteTimeout: cfg.HTTPServer.Timeout,
+			IdleTimeout:  cfg.HTTPServer.IdleTimeout,
+		}
+		go func() {
+			serverErr <- adminServer.ListenAndServe()
+		}()
+	}
+
+	exitCode := 0
+
+	drain := cfg.HTTPServer.DrainDelay
+
+	select {
+	case <-ctx.Done():
+		log.Info("shutdown signal received")
+	case err := <-serverErr:
 		log.Error("failed to start server", slog.String("error", err.Error()))
+		exitCode = 1
+		drain = 0
+	}
+
+	// A second signal ends the process without waiting for the drain.
+	stop()
+
+	// Shut down in dependency order: stop taking requests first, then the
+	// workers they feed, and close the storage last. Readiness fails for the
+	// drain delay before the listener closes, so that load balancers see it.
+	shuttingDown.Store(true)
+
+	if drain > 0 {
+		log.Info("draining http server", slog.Duration("delay", drain))
+		time.Sleep(drain)
+	}
+
+	log.Info("stopping http server", slog.Duration("timeout", cfg.HTTPServer.ShutdownTimeout))
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTPServer.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Error("failed to drain http server", slog.String("error", err.Error()))
+		server.Close()
+	}
+
+	if adminServer != nil {
+		if err := adminServer.Shutdown(shutdownCtx); err != nil {
+			log.Error("failed to drain admin server", slog.String("error", err.Error()))
+			adminServer.Close()
+		}
+	}
+
+	log.Info("stopping background workers")
+
+	clicks.Stop()
+	sessionSweeper.Stop()
+	urlSweeper.Stop()
+
+	log.Info("closing storage")
+
+	if err := storage.Close(); err != nil {
+		log.Error("failed to close storage", slog.String("error", err.Error()))
+	}
+
+	log.Info("server stopped")
+
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+}
+
+func setupStorage(cfg *config.Config) (storage.Store, error) {
+	switch cfg.Storage.Driver {
+	case config.StorageDriverMemory:
+		return memory.New(), nil
+	case config.StorageDriverPostgres:
+		return postgres.New(cfg.Storage.DSN)
+	default:
+		return sqlite.New(cfg.StoragePath)
 	}
 }
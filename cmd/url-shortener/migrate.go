@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/lib/logger"
+	"url-shortener/internal/storage/migrator"
+	"url-shortener/internal/storage/postgres"
+	"url-shortener/internal/storage/sqlite"
+)
+
+const migrateUsage = "usage: url-shortener migrate up|down|status"
+
+// runMigrate handles `url-shortener migrate up|down|status`.
+func runMigrate(cfg *config.Config, log *logger.Logger, args []string) {
+	if len(args) != 1 {
+		log.Fatal(migrateUsage)
+	}
+
+	m, err := setupMigrator(cfg)
+	if err != nil {
+		log.Fatal("failed to init migrator", slog.String("error", err.Error()))
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := m.Up()
+		for _, migration := range applied {
+			log.Info("migration applied",
+				slog.Int64("version", migration.Version),
+				slog.String("name", migration.Name),
+			)
+		}
+		if err != nil {
+			log.Fatal("failed to apply migrations", slog.String("error", err.Error()))
+		}
+		if len(applied) == 0 {
+			log.Info("no pending migrations")
+		}
+	case "down":
+		reverted, err := m.Down()
+		if err != nil {
+			log.Fatal("failed to revert migration", slog.String("error", err.Error()))
+		}
+		if reverted == nil {
+			log.Info("no applied migrations")
+			return
+		}
+		log.Info("migration reverted",
+			slog.Int64("version", reverted.Version),
+			slog.String("name", reverted.Name),
+		)
+	case "status":
+		statuses, err := m.Status()
+		if err != nil {
+			log.Fatal("failed to get migration status", slog.String("error", err.Error()))
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, status := range statuses {
+			state, appliedAt := "pending", ""
+			if status.Applied {
+				state, appliedAt = "applied", status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+		}
+		w.Flush()
+	default:
+		log.Fatal(migrateUsage)
+	}
+}
+
+func setupMigrator(cfg *config.Config) (*migrator.Migrator, error) {
+	switch cfg.Storage.Driver {
+	case config.StorageDriverMemory:
+		return nil, errors.New("memory storage has no schema to migrate")
+	case config.StorageDriverPostgres:
+		s, err := postgres.Open(cfg.Storage.DSN)
+		if err != nil {
+			return nil, err
+		}
+		return s.Migrator()
+	default:
+		s, err := sqlite.Open(cfg.StoragePath)
+		if err != nil {
+			return nil, err
+		}
+		return s.Migrator()
+	}
+}
@@ -0,0 +1,171 @@
+package bulk
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"time"
+	"url-shortener/internal/api/handlers/save"
+	"url-shortener/internal/api/middleware/auth"
+	"url-shortener/internal/api/response"
+	"url-shortener/internal/lib/logger"
+	"url-shortener/internal/lib/validation"
+	"url-shortener/internal/storage"
+)
+
+// chunkSize items share a storage transaction, unless the request is atomic.
+const chunkSize = 100
+
+type Request struct {
+	Items []save.Request `json:"items" validate:"required,min=1,max=1000"`
+	// Atomic saves every item or, if any of them fails, none at all.
+	Atomic bool `json:"atomic,omitempty"`
+}
+
+type Response struct {
+	response.Response
+	// Items holds the result of every request item, in order.
+	Items []Item `json:"items"`
+	Saved int    `json:"saved"`
+}
+
+type Item struct {
+	response.Response
+	Alias     string     `json:"alias,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.43.2 --name=URLBatcher
+type URLBatcher interface {
+	BeginURLBatch() (storage.URLBatch, error)
+}
+
+func New(
+	log *logger.Logger,
+	batcher URLBatcher,
+	aliases save.AliasGenerator,
+	policy save.AliasPolicy,
+	attempts int,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.url.bulk.New"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userId, ok := auth.UserID(r.Context())
+		if !ok {
+			log.Error("user id is missing from context")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error(response.CodeUnauthorized, "unauthorized"))
+			return
+		}
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(response.CodeInvalidRequest, "failed to decode request body"))
+			return
+		}
+
+		if errs := validation.Struct(req); errs != nil {
+			log.Info("invalid request", slog.Any("errors", errs))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.ValidationError(errs...))
+			return
+		}
+
+		size := chunkSize
+		if req.Atomic {
+			size = len(req.Items)
+		}
+
+		resp := Response{
+			Response: response.OK(),
+			Items:    make([]Item, len(req.Items)),
+		}
+
+		for start := 0; start < len(req.Items); start += size {
+			end := min(start+size, len(req.Items))
+			items, results := req.Items[start:end], resp.Items[start:end]
+
+			err := saveChunk(batcher, aliases, policy, attempts, items, userId, req.Atomic, results)
+			if err != nil {
+				log.Error("failed to save links", slog.Int("from", start), slog.String("error", err.Error()))
+				for i := range results {
+					results[i] = Item{Response: response.Error(response.CodeInternal, "failed to add url")}
+				}
+			}
+		}
+
+		for _, item := range resp.Items {
+			if item.Status == response.StatusOK {
+				resp.Saved++
+			}
+		}
+
+		log.Info("links added", slog.Int("saved", resp.Saved), slog.Int("items", len(req.Items)))
+
+		if req.Atomic && resp.Saved < len(req.Items) {
+			resp.Response = response.Error(response.CodeBulkFailed, "no links were saved")
+			render.Status(r, http.StatusUnprocessableEntity)
+		}
+
+		render.JSON(w, r, resp)
+	}
+}
+
+// saveChunk saves items in one batch, filling in their results. An atomic
+// chunk is rolled back if any of its items fails.
+func saveChunk(
+	batcher URLBatcher,
+	aliases save.AliasGenerator,
+	policy save.AliasPolicy,
+	attempts int,
+	items []save.Request,
+	userId int64,
+	atomic bool,
+	results []Item,
+) error {
+	b, err := batcher.BeginURLBatch()
+	if err != nil {
+		return err
+	}
+	defer b.Rollback()
+
+	failed := false
+	for i, item := range items {
+		res, err := save.Save(b, aliases, policy, attempts, item, userId)
+		if err != nil {
+			_, results[i].Response = save.ErrorResponse(err)
+			failed = true
+			continue
+		}
+
+		results[i] = Item{
+			Response: response.OK(),
+			Alias:    res.Alias,
+		}
+		if !res.ExpiresAt.IsZero() {
+			results[i].ExpiresAt = &res.ExpiresAt
+		}
+	}
+
+	if atomic && failed {
+		for i := range results {
+			if results[i].Status == response.StatusOK {
+				results[i] = Item{Response: response.Error(response.CodeBulkFailed, "not saved because another item failed")}
+			}
+		}
+
+		return b.Rollback()
+	}
+
+	return b.Commit()
+}
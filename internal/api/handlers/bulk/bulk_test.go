@@ -0,0 +1,222 @@
+package bulk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"url-shortener/internal/api/handlers/bulk/mocks"
+	"url-shortener/internal/api/middleware/auth"
+	"url-shortener/internal/api/response"
+	"url-shortener/internal/config"
+	"url-shortener/internal/lib/alias"
+	"url-shortener/internal/lib/logger/handlers"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/memory"
+)
+
+const testUserId int64 = 1
+
+var (
+	aliasConfig = config.Alias{
+		Strategy: config.AliasStrategyID,
+		Length:   6,
+		Alphabet: "abcdefghijklmnopqrstuvwxyz",
+		Attempts: 3,
+		Policy: config.AliasPolicy{
+			Charset:   "abcdefghijklmnopqrstuvwxyz_",
+			MinLength: 3,
+			MaxLength: 32,
+			Reserved:  []string{"links"},
+		},
+	}
+	aliases = alias.NewGenerator(aliasConfig)
+	policy  = alias.NewPolicy(aliasConfig.Policy)
+)
+
+func TestBulkHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		beginErr   error
+		respStatus int
+		respCode   string
+		itemCodes  []string
+		saved      []string
+	}{
+		{
+			name: "Success",
+			body: `{"items": [
+				{"url": "https://example.com/1", "alias": "first"},
+				{"url": "https://example.com/2", "ttl": "1h"}
+			]}`,
+			respStatus: http.StatusOK,
+			itemCodes:  []string{"", ""},
+			saved:      []string{"first"},
+		},
+		{
+			name: "Partial failure",
+			body: `{"items": [
+				{"url": "https://example.com/1", "alias": "first"},
+				{"url": "not a url"},
+				{"url": "https://example.com/3", "alias": "taken"},
+				{"url": "https://example.com/4", "alias": "links"},
+				{"url": "https://example.com/5", "alias": "first"}
+			]}`,
+			respStatus: http.StatusOK,
+			itemCodes: []string{
+				"",
+				response.CodeValidationFailed,
+				response.CodeAliasTaken,
+				response.CodeValidationFailed,
+				response.CodeAliasTaken,
+			},
+			saved: []string{"first"},
+		},
+		{
+			name: "Atomic",
+			body: `{"atomic": true, "items": [
+				{"url": "https://example.com/1", "alias": "first"},
+				{"url": "https://example.com/2", "alias": "second"}
+			]}`,
+			respStatus: http.StatusOK,
+			itemCodes:  []string{"", ""},
+			saved:      []string{"first", "second"},
+		},
+		{
+			name: "Atomic failure",
+			body: `{"atomic": true, "items": [
+				{"url": "https://example.com/1", "alias": "first"},
+				{"url": "https://example.com/2", "alias": "taken"}
+			]}`,
+			respStatus: http.StatusUnprocessableEntity,
+			respCode:   response.CodeBulkFailed,
+			itemCodes:  []string{response.CodeBulkFailed, response.CodeAliasTaken},
+		},
+		{
+			name: "Storage error",
+			body: `{"items": [
+				{"url": "https://example.com/1", "alias": "first"}
+			]}`,
+			beginErr:   errors.New("unexpected error"),
+			respStatus: http.StatusOK,
+			itemCodes:  []string{response.CodeInternal},
+		},
+		{
+			name:       "No items",
+			body:       `{"items": []}`,
+			respStatus: http.StatusBadRequest,
+			respCode:   response.CodeValidationFailed,
+		},
+		{
+			name:       "Malformed body",
+			body:       `{"items": [`,
+			respStatus: http.StatusBadRequest,
+			respCode:   response.CodeInvalidRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			store := memory.New()
+			_, err := store.SaveURL("https://example.com/taken", "taken", testUserId, storage.URLOptions{})
+			require.NoError(t, err)
+
+			batcherMock := mocks.NewURLBatcher(t)
+			if tc.itemCodes != nil {
+				batcherMock.On("BeginURLBatch").
+					Return(func() (storage.URLBatch, error) {
+						if tc.beginErr != nil {
+							return nil, tc.beginErr
+						}
+						return store.BeginURLBatch()
+					}).
+					Once()
+			}
+
+			handler := New(handlers.NewDiscardLogger(), batcherMock, aliases, policy, aliasConfig.Attempts)
+
+			req, err := http.NewRequest(http.MethodPost, "/links/bulk", bytes.NewReader([]byte(tc.body)))
+			require.NoError(t, err)
+
+			req = req.WithContext(auth.WithUserID(req.Context(), testUserId))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respStatus, rr.Code)
+
+			var resp Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respCode, resp.Code)
+
+			var itemCodes []string
+			saved := 0
+			for _, item := range resp.Items {
+				itemCodes = append(itemCodes, item.Code)
+				if item.Status == response.StatusOK {
+					require.NotEmpty(t, item.Alias)
+					saved++
+				}
+			}
+			require.Equal(t, tc.itemCodes, itemCodes)
+			require.Equal(t, saved, resp.Saved)
+
+			for _, alias := range tc.saved {
+				_, err := store.GetURL(alias)
+				require.NoError(t, err)
+			}
+			if tc.respCode == response.CodeBulkFailed {
+				_, err := store.GetURL("first")
+				require.ErrorIs(t, err, storage.ErrURLNotFound)
+			}
+		})
+	}
+}
+
+func TestBulkHandler_Chunks(t *testing.T) {
+	store := memory.New()
+
+	batcherMock := mocks.NewURLBatcher(t)
+	batcherMock.On("BeginURLBatch").
+		Return(func() (storage.URLBatch, error) {
+			return store.BeginURLBatch()
+		}).
+		Times(3)
+
+	items := make([]string, 2*chunkSize+1)
+	for i := range items {
+		items[i] = fmt.Sprintf(`{"url": "https://example.com/%d"}`, i)
+	}
+
+	handler := New(handlers.NewDiscardLogger(), batcherMock, aliases, policy, aliasConfig.Attempts)
+
+	body := `{"items": [` + strings.Join(items, ",") + `]}`
+	req, err := http.NewRequest(http.MethodPost, "/links/bulk", bytes.NewReader([]byte(body)))
+	require.NoError(t, err)
+
+	req = req.WithContext(auth.WithUserID(req.Context(), testUserId))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp Response
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Equal(t, len(items), resp.Saved)
+
+	last := resp.Items[len(items)-1]
+	got, err := store.GetURL(last.Alias)
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("https://example.com/%d", len(items)-1), got)
+}
@@ -1,18 +1,21 @@
 package delete
 
 import (
+	"errors"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"log/slog"
 	"net/http"
+	"url-shortener/internal/api/middleware/auth"
 	"url-shortener/internal/api/response"
 	"url-shortener/internal/lib/logger"
+	"url-shortener/internal/storage"
 )
 
 //go:generate go run github.com/vektra/mockery/v2@v2.43.2 --name=URLRemover
 type URLRemover interface {
-	DeleteURL(alias string) error
+	DeleteURL(alias string, userId int64) error
 }
 
 func New(log *logger.Logger, urlRemover URLRemover) http.HandlerFunc {
@@ -24,24 +27,44 @@ func New(log *logger.Logger, urlRemover URLRemover) http.HandlerFunc {
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
+		userId, ok := auth.UserID(r.Context())
+		if !ok {
+			log.Error("user id is missing from context")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error(response.CodeUnauthorized, "unauthorized"))
+			return
+		}
+
 		alias := chi.URLParam(r, "alias")
 		if alias == "" {
 			log.Info("alias is empty")
-			render.JSON(w, r, response.Error("invalid request"))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(response.CodeInvalidRequest, "invalid request"))
 			return
 		}
 
-		err := urlRemover.DeleteURL(alias)
+		err := urlRemover.DeleteURL(alias, userId)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error(response.CodeNotFound, "not found"))
+			return
+		}
+		if errors.Is(err, storage.ErrForbidden) {
+			log.Info("url is owned by another user", slog.String("alias", alias))
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, response.Error(response.CodeForbidden, "forbidden"))
+			return
+		}
 		if err != nil {
 			log.Error("failed to delete url", slog.String("error", err.Error()))
-			render.JSON(w, r, response.Error("delete url error"))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error(response.CodeInternal, "delete url error"))
 			return
 		}
 
 		log.Info("url deleted", slog.String("alias", alias))
 
-		// TODO - return error if url not exists
-
 		render.JSON(w, r, response.OK())
 	}
 }
@@ -9,33 +9,52 @@ import (
 	"net/http/httptest"
 	"testing"
 	"url-shortener/internal/api/handlers/delete/mocks"
+	"url-shortener/internal/api/middleware/auth"
 	"url-shortener/internal/api/response"
 	"url-shortener/internal/lib/logger/handlers"
+	"url-shortener/internal/storage"
 )
 
+const testUserId int64 = 1
+
 func TestDeleteHandler(t *testing.T) {
 	tests := []struct {
-		name      string
-		alias     string
-		url       string
-		respError string
-		mockError error
+		name       string
+		alias      string
+		url        string
+		respError  string
+		respCode   string
+		respStatus int
+		mockError  error
 	}{
 		{
-			name:  "Success",
-			alias: "test_alias",
+			name:       "Success",
+			alias:      "test_alias",
+			respStatus: http.StatusOK,
+		},
+		{
+			name:       "Failed to delete",
+			alias:      "test_alias",
+			respError:  "delete url error",
+			respCode:   response.CodeInternal,
+			respStatus: http.StatusInternalServerError,
+			mockError:  errors.New("internal error"),
 		},
 		{
-			name:      "Failed to delete",
-			alias:     "test_alias",
-			respError: "delete url error",
-			mockError: errors.New("internal error"),
+			name:       "Alias not found",
+			alias:      "non_existing_alias",
+			respError:  "not found",
+			respCode:   response.CodeNotFound,
+			respStatus: http.StatusNotFound,
+			mockError:  storage.ErrURLNotFound,
 		},
 		{
-			name:      "Alias not found",
-			alias:     "non_existing_alias",
-			respError: "",
-			mockError: nil,
+			name:       "Owned by another user",
+			alias:      "foreign_alias",
+			respError:  "forbidden",
+			respCode:   response.CodeForbidden,
+			respStatus: http.StatusForbidden,
+			mockError:  storage.ErrForbidden,
 		},
 	}
 
@@ -47,7 +66,7 @@ func TestDeleteHandler(t *testing.T) {
 			urlRemoverMock := mocks.NewURLRemover(t)
 
 			if tc.respError == "" || tc.mockError != nil {
-				urlRemoverMock.On("DeleteURL", tc.alias).
+				urlRemoverMock.On("DeleteURL", tc.alias, testUserId).
 					Return(tc.mockError).
 					Once()
 			}
@@ -60,6 +79,8 @@ func TestDeleteHandler(t *testing.T) {
 			req, err := http.NewRequest(http.MethodDelete, "/"+tc.alias, nil)
 			require.NoError(t, err)
 
+			req = req.WithContext(auth.WithUserID(req.Context(), testUserId))
+
 			rr := httptest.NewRecorder()
 			router.ServeHTTP(rr, req)
 
@@ -68,12 +89,13 @@ func TestDeleteHandler(t *testing.T) {
 			var resp response.Response
 
 			if tc.alias != "" {
-				require.Equal(t, rr.Code, http.StatusOK)
+				require.Equal(t, tc.respStatus, rr.Code)
 				require.NoError(t, json.Unmarshal([]byte(body), &resp))
 			}
 
 			if tc.respError != "" {
 				require.Equal(t, tc.respError, resp.Error)
+				require.Equal(t, tc.respCode, resp.Code)
 			} else {
 				require.Empty(t, resp.Error)
 			}
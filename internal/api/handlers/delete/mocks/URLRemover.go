@@ -9,17 +9,17 @@ type URLRemover struct {
 	mock.Mock
 }
 
-// DeleteURL provides a mock function with given fields: alias
-func (_m *URLRemover) DeleteURL(alias string) error {
-	ret := _m.Called(alias)
+// DeleteURL provides a mock function with given fields: alias, userId
+func (_m *URLRemover) DeleteURL(alias string, userId int64) error {
+	ret := _m.Called(alias, userId)
 
 	if len(ret) == 0 {
 		panic("no return value specified for DeleteURL")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string) error); ok {
-		r0 = rf(alias)
+	if rf, ok := ret.Get(0).(func(string, int64) error); ok {
+		r0 = rf(alias, userId)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -0,0 +1,119 @@
+package export
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/api/middleware/auth"
+	"url-shortener/internal/api/response"
+	"url-shortener/internal/lib/linkfile"
+	"url-shortener/internal/lib/logger"
+	"url-shortener/internal/lib/validation"
+	"url-shortener/internal/storage"
+)
+
+type Query struct {
+	Format string `json:"format" validate:"oneof=csv jsonl"`
+}
+
+var contentTypes = map[string]string{
+	linkfile.FormatCSV:   "text/csv; charset=utf-8",
+	linkfile.FormatJSONL: "application/x-ndjson",
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.43.2 --name=URLWalker
+type URLWalker interface {
+	WalkURLs(userId int64, visit func(storage.URLInfo) error) error
+}
+
+// New streams the links of the user as they are read from storage. Once the
+// first records are sent, a failure can only cut the export short.
+func New(log *logger.Logger, urlWalker URLWalker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.url.export.New"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userId, ok := auth.UserID(r.Context())
+		if !ok {
+			log.Error("user id is missing from context")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error(response.CodeUnauthorized, "unauthorized"))
+			return
+		}
+
+		query := Query{Format: r.URL.Query().Get("format")}
+		if query.Format == "" {
+			query.Format = linkfile.FormatCSV
+		}
+
+		if errs := validation.Struct(query); errs != nil {
+			log.Info("invalid query", slog.Any("errors", errs))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.ValidationError(errs...))
+			return
+		}
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		lw, err := linkfile.NewWriter(ww, query.Format)
+		if err != nil {
+			log.Error("failed to create writer", slog.String("error", err.Error()))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error(response.CodeInternal, "internal error"))
+			return
+		}
+
+		ww.Header().Set("Content-Type", contentTypes[query.Format])
+		ww.Header().Set("Content-Disposition", `attachment; filename="links.`+query.Format+`"`)
+
+		exported := 0
+		err = urlWalker.WalkURLs(userId, func(u storage.URLInfo) error {
+			exported++
+			return lw.Write(record(u))
+		})
+		if err == nil {
+			err = lw.Flush()
+		}
+		if err != nil {
+			log.Error("failed to export links", slog.Int("exported", exported), slog.String("error", err.Error()))
+
+			if ww.BytesWritten() == 0 {
+				ww.Header().Del("Content-Disposition")
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(ww, r, response.Error(response.CodeInternal, "internal error"))
+			}
+			return
+		}
+
+		log.Info("links exported", slog.Int("exported", exported))
+	}
+}
+
+func record(u storage.URLInfo) linkfile.Record {
+	rec := linkfile.Record{
+		Alias:     u.Alias,
+		URL:       u.URL,
+		Clicks:    u.Clicks,
+		MaxClicks: u.MaxClicks,
+		Tags:      u.Tags,
+
+		RedirectType:     u.RedirectType,
+		QueryPassthrough: u.QueryPassthrough,
+		PathPassthrough:  u.PathPassthrough,
+	}
+	if !u.CreatedAt.IsZero() {
+		createdAt := u.CreatedAt.UTC()
+		rec.CreatedAt = &createdAt
+	}
+	if !u.ExpiresAt.IsZero() {
+		expiresAt := u.ExpiresAt.UTC()
+		rec.ExpiresAt = &expiresAt
+	}
+
+	return rec
+}
@@ -0,0 +1,127 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"url-shortener/internal/api/handlers/export/mocks"
+	"url-shortener/internal/api/middleware/auth"
+	"url-shortener/internal/api/response"
+	"url-shortener/internal/lib/logger/handlers"
+	"url-shortener/internal/storage"
+)
+
+const testUserId int64 = 1
+
+func TestExportHandler(t *testing.T) {
+	createdAt := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	urls := []storage.URLInfo{
+		{
+			ID: 1, Alias: "first", URL: "https://example.com/1", CreatedAt: createdAt, Clicks: 3, Tags: []string{"docs", "work"},
+			Passthrough: storage.Passthrough{QueryPassthrough: storage.QueryKeep, PathPassthrough: true},
+		},
+		{ID: 2, Alias: "second", URL: "https://example.com/2", ExpiresAt: createdAt, MaxClicks: 5, RedirectType: 307},
+	}
+
+	tests := []struct {
+		name        string
+		query       string
+		mockError   error
+		respStatus  int
+		contentType string
+		respBody    string
+		respCode    string
+	}{
+		{
+			name:        "CSV",
+			query:       "format=csv",
+			respStatus:  http.StatusOK,
+			contentType: "text/csv; charset=utf-8",
+			respBody: "alias,url,created_at,expires_at,clicks,max_clicks,tags,redirect_type,query_passthrough,path_passthrough\n" +
+				"first,https://example.com/1,2024-03-10T12:00:00Z,,3,0,\"docs,work\",,keep,true\n" +
+				"second,https://example.com/2,,2024-03-10T12:00:00Z,0,5,,307,,\n",
+		},
+		{
+			name:        "CSV by default",
+			respStatus:  http.StatusOK,
+			contentType: "text/csv; charset=utf-8",
+			respBody: "alias,url,created_at,expires_at,clicks,max_clicks,tags,redirect_type,query_passthrough,path_passthrough\n" +
+				"first,https://example.com/1,2024-03-10T12:00:00Z,,3,0,\"docs,work\",,keep,true\n" +
+				"second,https://example.com/2,,2024-03-10T12:00:00Z,0,5,,307,,\n",
+		},
+		{
+			name:        "JSON Lines",
+			query:       "format=jsonl",
+			respStatus:  http.StatusOK,
+			contentType: "application/x-ndjson",
+			respBody: `{"alias":"first","url":"https://example.com/1","created_at":"2024-03-10T12:00:00Z","clicks":3,"tags":["docs","work"],"query_passthrough":"keep","path_passthrough":true}` + "\n" +
+				`{"alias":"second","url":"https://example.com/2","expires_at":"2024-03-10T12:00:00Z","clicks":0,"max_clicks":5,"redirect_type":307}` + "\n",
+		},
+		{
+			name:       "Unknown format",
+			query:      "format=xml",
+			respStatus: http.StatusBadRequest,
+			respCode:   response.CodeValidationFailed,
+		},
+		{
+			name:       "Storage error",
+			query:      "format=csv",
+			mockError:  errors.New("unexpected error"),
+			respStatus: http.StatusInternalServerError,
+			respCode:   response.CodeInternal,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlWalkerMock := mocks.NewURLWalker(t)
+
+			if tc.respCode != response.CodeValidationFailed {
+				urlWalkerMock.On("WalkURLs", testUserId, mock.Anything).
+					Return(func(_ int64, visit func(storage.URLInfo) error) error {
+						if tc.mockError != nil {
+							return tc.mockError
+						}
+						for _, u := range urls {
+							if err := visit(u); err != nil {
+								return err
+							}
+						}
+						return nil
+					}).
+					Once()
+			}
+
+			handler := New(handlers.NewDiscardLogger(), urlWalkerMock)
+
+			req, err := http.NewRequest(http.MethodGet, "/links/export?"+tc.query, nil)
+			require.NoError(t, err)
+
+			req = req.WithContext(auth.WithUserID(req.Context(), testUserId))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respStatus, rr.Code)
+
+			if tc.respCode != "" {
+				var resp response.Response
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+				require.Equal(t, tc.respCode, resp.Code)
+				return
+			}
+
+			require.Equal(t, tc.contentType, rr.Header().Get("Content-Type"))
+			require.Equal(t, tc.respBody, rr.Body.String())
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package health
+
+import (
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"url-shortener/internal/api/response"
+	"url-shortener/internal/lib/logger"
+)
+
+type Response struct {
+	response.Response
+	MigrationVersion int64 `json:"migration_version"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.43.2 --name=StorageChecker
+type StorageChecker interface {
+	Ping() error
+	SchemaVersion() (int64, error)
+}
+
+// NewLiveness reports that the process is up. It deliberately checks
+// nothing else, so a slow database never gets the process restarted.
+func NewLiveness() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, response.OK())
+	}
+}
+
+// NewReadiness reports whether the server should receive traffic: the
+// storage must answer and the server must not be shutting down.
+func NewReadiness(log *logger.Logger, storageChecker StorageChecker, shuttingDown *atomic.Bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.url.health.NewReadiness"
+
+		log := log.With(slog.String("fn", fn))
+
+		if shuttingDown.Load() {
+			render.Status(r, http.StatusServiceUnavailable)
+			render.JSON(w, r, response.Error(response.CodeUnavailable, "shutting down"))
+			return
+		}
+
+		if err := storageChecker.Ping(); err != nil {
+			log.Error("storage ping failed", slog.String("error", err.Error()))
+			render.Status(r, http.StatusServiceUnavailable)
+			render.JSON(w, r, response.Error(response.CodeUnavailable, "storage unavailable"))
+			return
+		}
+
+		version, err := storageChecker.SchemaVersion()
+		if err != nil {
+			log.Error("failed to read schema version", slog.String("error", err.Error()))
+			render.Status(r, http.StatusServiceUnavailable)
+			render.JSON(w, r, response.Error(response.CodeUnavailable, "storage unavailable"))
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response:         response.OK(),
+			MigrationVersion: version,
+		})
+	}
+}
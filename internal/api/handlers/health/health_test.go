@@ -0,0 +1,98 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"url-shortener/internal/api/handlers/health/mocks"
+	"url-shortener/internal/api/response"
+	"url-shortener/internal/lib/logger/handlers"
+)
+
+func TestLivenessHandler(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/healthz", nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	NewLiveness().ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestReadinessHandler(t *testing.T) {
+	tests := []struct {
+		name         string
+		shuttingDown bool
+		pingError    error
+		versionError error
+		version      int64
+		respError    string
+		respStatus   int
+	}{
+		{
+			name:       "Ready",
+			version:    4,
+			respStatus: http.StatusOK,
+		},
+		{
+			name:         "Shutting down",
+			shuttingDown: true,
+			respError:    "shutting down",
+			respStatus:   http.StatusServiceUnavailable,
+		},
+		{
+			name:       "Ping fails",
+			pingError:  errors.New("connection refused"),
+			respError:  "storage unavailable",
+			respStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:         "Version fails",
+			versionError: errors.New("no such table"),
+			respError:    "storage unavailable",
+			respStatus:   http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			storageCheckerMock := mocks.NewStorageChecker(t)
+
+			if !tc.shuttingDown {
+				storageCheckerMock.On("Ping").Return(tc.pingError).Once()
+			}
+			if !tc.shuttingDown && tc.pingError == nil {
+				storageCheckerMock.On("SchemaVersion").Return(tc.version, tc.versionError).Once()
+			}
+
+			var shuttingDown atomic.Bool
+			shuttingDown.Store(tc.shuttingDown)
+
+			handler := NewReadiness(handlers.NewDiscardLogger(), storageCheckerMock, &shuttingDown)
+
+			req, err := http.NewRequest(http.MethodGet, "/readyz", nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respStatus, rr.Code)
+
+			var resp Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			if tc.respError != "" {
+				require.Equal(t, response.CodeUnavailable, resp.Code)
+			}
+			require.Equal(t, tc.version, resp.MigrationVersion)
+		})
+	}
+}
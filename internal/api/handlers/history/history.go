@@ -0,0 +1,97 @@
+package history
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"time"
+	"url-shortener/internal/api/middleware/auth"
+	"url-shortener/internal/api/response"
+	"url-shortener/internal/lib/logger"
+	"url-shortener/internal/storage"
+)
+
+type Response struct {
+	response.Response
+	Alias string `json:"alias"`
+	// Changes lists the destination changes of the link, newest first.
+	Changes []Change `json:"changes"`
+}
+
+type Change struct {
+	OldURL    string    `json:"old_url"`
+	NewURL    string    `json:"new_url"`
+	ChangedAt time.Time `json:"changed_at"`
+	ChangedBy int64     `json:"changed_by"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.43.2 --name=HistoryGetter
+type HistoryGetter interface {
+	GetURLHistory(alias string, userId int64) ([]storage.URLChange, error)
+}
+
+func New(log *logger.Logger, historyGetter HistoryGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.url.history.New"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userId, ok := auth.UserID(r.Context())
+		if !ok {
+			log.Error("user id is missing from context")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error(response.CodeUnauthorized, "unauthorized"))
+			return
+		}
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias is empty")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(response.CodeInvalidRequest, "invalid request"))
+			return
+		}
+
+		history, err := historyGetter.GetURLHistory(alias, userId)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error(response.CodeNotFound, "not found"))
+			return
+		}
+		if errors.Is(err, storage.ErrForbidden) {
+			log.Info("url is owned by another user", slog.String("alias", alias))
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, response.Error(response.CodeForbidden, "forbidden"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url history", slog.String("error", err.Error()))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error(response.CodeInternal, "internal error"))
+			return
+		}
+
+		resp := Response{
+			Response: response.OK(),
+			Alias:    alias,
+			Changes:  make([]Change, 0, len(history)),
+		}
+		for _, c := range history {
+			resp.Changes = append(resp.Changes, Change{
+				OldURL:    c.OldURL,
+				NewURL:    c.NewURL,
+				ChangedAt: c.ChangedAt.UTC(),
+				ChangedBy: c.ChangedBy,
+			})
+		}
+
+		render.JSON(w, r, resp)
+	}
+}
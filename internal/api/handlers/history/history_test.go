@@ -0,0 +1,104 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"url-shortener/internal/api/handlers/history/mocks"
+	"url-shortener/internal/api/middleware/auth"
+	"url-shortener/internal/api/response"
+	"url-shortener/internal/lib/logger/handlers"
+	"url-shortener/internal/storage"
+)
+
+const testUserId int64 = 1
+
+func TestHistoryHandler(t *testing.T) {
+	changedAt := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		history     []storage.URLChange
+		respChanges []Change
+		respError   string
+		respCode    string
+		respStatus  int
+		mockError   error
+	}{
+		{
+			name: "Success",
+			history: []storage.URLChange{
+				{OldURL: "https://example.com/v1", NewURL: "https://example.com/v2", ChangedAt: changedAt, ChangedBy: testUserId},
+			},
+			respChanges: []Change{
+				{OldURL: "https://example.com/v1", NewURL: "https://example.com/v2", ChangedAt: changedAt, ChangedBy: testUserId},
+			},
+			respStatus: http.StatusOK,
+		},
+		{
+			name:        "Never changed",
+			respChanges: []Change{},
+			respStatus:  http.StatusOK,
+		},
+		{
+			name:       "Alias not found",
+			respError:  "not found",
+			respCode:   response.CodeNotFound,
+			respStatus: http.StatusNotFound,
+			mockError:  storage.ErrURLNotFound,
+		},
+		{
+			name:       "Owned by another user",
+			respError:  "forbidden",
+			respCode:   response.CodeForbidden,
+			respStatus: http.StatusForbidden,
+			mockError:  storage.ErrForbidden,
+		},
+		{
+			name:       "Storage error",
+			respError:  "internal error",
+			respCode:   response.CodeInternal,
+			respStatus: http.StatusInternalServerError,
+			mockError:  errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			historyGetterMock := mocks.NewHistoryGetter(t)
+			historyGetterMock.On("GetURLHistory", "test_alias", testUserId).
+				Return(tc.history, tc.mockError).
+				Once()
+
+			router := chi.NewRouter()
+			router.Get("/{alias}/history", New(handlers.NewDiscardLogger(), historyGetterMock))
+
+			req, err := http.NewRequest(http.MethodGet, "/test_alias/history", nil)
+			require.NoError(t, err)
+
+			req = req.WithContext(auth.WithUserID(req.Context(), testUserId))
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respStatus, rr.Code)
+
+			var resp Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.respCode, resp.Code)
+			if tc.respError == "" {
+				require.Equal(t, tc.respChanges, resp.Changes)
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+// Package ingest implements POST /links/import; import itself is a keyword.
+package ingest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"io"
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/api/handlers/save"
+	"url-shortener/internal/api/middleware/auth"
+	"url-shortener/internal/api/response"
+	"url-shortener/internal/lib/linkfile"
+	"url-shortener/internal/lib/logger"
+	"url-shortener/internal/lib/validation"
+	"url-shortener/internal/storage"
+)
+
+// Conflict policies for records whose alias is taken.
+const (
+	ConflictSkip      = "skip"
+	ConflictOverwrite = "overwrite"
+	ConflictRename    = "rename"
+)
+
+type Query struct {
+	Format   string `json:"format" validate:"oneof=csv jsonl"`
+	Conflict string `json:"conflict" validate:"oneof=skip overwrite rename"`
+}
+
+type Response struct {
+	response.Response
+	Saved       int `json:"saved"`
+	Overwritten int `json:"overwritten"`
+	Skipped     int `json:"skipped"`
+	// Renamed lists the records saved under another alias than their own.
+	Renamed []Renamed `json:"renamed,omitempty"`
+	Failed  []Failure `json:"failed,omitempty"`
+}
+
+type Renamed struct {
+	Alias    string `json:"alias"`
+	NewAlias string `json:"new_alias"`
+}
+
+type Failure struct {
+	response.Response
+	// Record is the position of the record in the file, counting from 1.
+	Record int    `json:"record"`
+	Alias  string `json:"alias,omitempty"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.43.2 --name=URLImporter
+type URLImporter interface {
+	NextURLID() (int64, error)
+	SaveURL(urlToSave string, alias string, userId int64, opts storage.URLOptions) (int64, error)
+	ReplaceURL(urlToSave string, alias string, userId int64, opts storage.URLOptions) (int64, error)
+}
+
+type outcome int
+
+const (
+	saved outcome = iota
+	overwritten
+	skipped
+	renamed
+)
+
+func New(
+	log *logger.Logger,
+	urlImporter URLImporter,
+	aliases save.AliasGenerator,
+	policy save.AliasPolicy,
+	attempts int,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.url.ingest.New"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userId, ok := auth.UserID(r.Context())
+		if !ok {
+			log.Error("user id is missing from context")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error(response.CodeUnauthorized, "unauthorized"))
+			return
+		}
+
+		params := r.URL.Query()
+		query := Query{
+			Format:   params.Get("format"),
+			Conflict: params.Get("conflict"),
+		}
+		if query.Format == "" {
+			query.Format = linkfile.FormatCSV
+		}
+		if query.Conflict == "" {
+			query.Conflict = ConflictSkip
+		}
+
+		if errs := validation.Struct(query); errs != nil {
+			log.Info("invalid query", slog.Any("errors", errs))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.ValidationError(errs...))
+			return
+		}
+
+		lr, err := linkfile.NewReader(r.Body, query.Format)
+		if err != nil {
+			log.Info("failed to read file", slog.String("error", err.Error()))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(response.CodeInvalidRequest, "failed to read file"))
+			return
+		}
+
+		resp := Response{Response: response.OK()}
+
+		for n := 1; ; n++ {
+			rec, err := lr.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if errors.Is(err, linkfile.ErrInvalidRecord) {
+				resp.Failed = append(resp.Failed, Failure{
+					Response: response.Error(response.CodeInvalidRequest, err.Error()),
+					Record:   n,
+				})
+				continue
+			}
+			if err != nil {
+				// The records so far are stored; say how far the import got.
+				log.Info("failed to read file", slog.Int("record", n), slog.String("error", err.Error()))
+				resp.Response = response.Error(response.CodeInvalidRequest, fmt.Sprintf("failed to read record %d", n))
+				render.Status(r, http.StatusBadRequest)
+				break
+			}
+
+			alias, res, err := importRecord(urlImporter, aliases, policy, attempts, query.Conflict, rec, userId)
+			if err != nil {
+				failure := Failure{Record: n, Alias: rec.Alias}
+				if errors.Is(err, storage.ErrForbidden) {
+					failure.Response = response.Error(response.CodeForbidden, "alias is owned by another user")
+				} else {
+					_, failure.Response = save.ErrorResponse(err)
+				}
+				if failure.Code == response.CodeInternal {
+					log.Error("failed to import link", slog.Int("record", n), slog.String("error", err.Error()))
+				}
+
+				resp.Failed = append(resp.Failed, failure)
+				continue
+			}
+
+			switch res {
+			case saved:
+				resp.Saved++
+			case overwritten:
+				resp.Overwritten++
+			case skipped:
+				resp.Skipped++
+			case renamed:
+				resp.Saved++
+				resp.Renamed = append(resp.Renamed, Renamed{Alias: rec.Alias, NewAlias: alias})
+			}
+		}
+
+		log.Info("links imported",
+			slog.Int("saved", resp.Saved),
+			slog.Int("overwritten", resp.Overwritten),
+			slog.Int("skipped", resp.Skipped),
+			slog.Int("failed", len(resp.Failed)),
+		)
+
+		render.JSON(w, r, resp)
+	}
+}
+
+// importRecord saves one record, resolving a taken alias by the conflict
+// policy. It returns the alias the link ended up under.
+func importRecord(
+	urlImporter URLImporter,
+	aliases save.AliasGenerator,
+	policy save.AliasPolicy,
+	attempts int,
+	conflict string,
+	rec linkfile.Record,
+	userId int64,
+) (string, outcome, error) {
+	if errs := validation.Struct(rec); errs != nil {
+		return "", 0, &save.InvalidError{Errors: errs}
+	}
+
+	opts := storage.URLOptions{
+		MaxClicks: rec.MaxClicks,
+		Tags:      rec.Tags,
+		Clicks:    rec.Clicks,
+
+		RedirectType: rec.RedirectType,
+		Passthrough: storage.Passthrough{
+			QueryPassthrough: rec.QueryPassthrough,
+			PathPassthrough:  rec.PathPassthrough,
+		},
+	}
+	if rec.ExpiresAt != nil {
+		opts.ExpiresAt = *rec.ExpiresAt
+	}
+	if rec.CreatedAt != nil {
+		opts.CreatedAt = *rec.CreatedAt
+	}
+
+	alias := policy.Normalize(rec.Alias)
+	if alias == "" {
+		alias, _, err := save.SaveGenerated(urlImporter, aliases, policy, attempts, rec.URL, userId, opts)
+		return alias, saved, err
+	}
+
+	if fieldErr := policy.Check(alias); fieldErr != nil {
+		return "", 0, &save.InvalidError{Errors: []validation.FieldError{*fieldErr}}
+	}
+
+	_, err := urlImporter.SaveURL(rec.URL, alias, userId, opts)
+	if !errors.Is(err, storage.ErrURLExists) {
+		return alias, saved, err
+	}
+
+	switch conflict {
+	case ConflictOverwrite:
+		// The record replaces the whole link, settings left out of it included.
+		if _, err := urlImporter.ReplaceURL(rec.URL, alias, userId, opts); err != nil {
+			return "", 0, err
+		}
+		return alias, overwritten, nil
+	case ConflictRename:
+		newAlias, err := rename(urlImporter, aliases, policy, attempts, alias, rec.URL, userId, opts)
+		return newAlias, renamed, err
+	default:
+		return alias, skipped, nil
+	}
+}
+
+// rename saves the link under the first free alias of alias-2, alias-3 and
+// so on, falling back to a generated alias when those are taken or rejected
+// by the policy.
+func rename(
+	urlImporter URLImporter,
+	aliases save.AliasGenerator,
+	policy save.AliasPolicy,
+	attempts int,
+	alias string,
+	urlToSave string,
+	userId int64,
+	opts storage.URLOptions,
+) (string, error) {
+	for n := 2; n <= attempts+1; n++ {
+		candidate := fmt.Sprintf("%s-%d", alias, n)
+		if policy.Check(candidate) != nil {
+			break
+		}
+
+		_, err := urlImporter.SaveURL(urlToSave, candidate, userId, opts)
+		if errors.Is(err, storage.ErrURLExists) {
+			continue
+		}
+
+		return candidate, err
+	}
+
+	newAlias, _, err := save.SaveGenerated(urlImporter, aliases, policy, attempts, urlToSave, userId, opts)
+
+	return newAlias, err
+}
@@ -0,0 +1,306 @@
+package ingest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"url-shortener/internal/api/handlers/ingest/mocks"
+	"url-shortener/internal/api/middleware/auth"
+	"url-shortener/internal/api/response"
+	"url-shortener/internal/config"
+	"url-shortener/internal/lib/alias"
+	"url-shortener/internal/lib/logger/handlers"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/memory"
+)
+
+const (
+	testUserId  int64 = 1
+	otherUserId int64 = 2
+)
+
+var (
+	aliasConfig = config.Alias{
+		Strategy: config.AliasStrategyID,
+		Length:   6,
+		Alphabet: "abcdefghijklmnopqrstuvwxyz",
+		Attempts: 3,
+		Policy: config.AliasPolicy{
+			Charset:   "abcdefghijklmnopqrstuvwxyz0123456789-_",
+			MinLength: 3,
+			MaxLength: 32,
+			Reserved:  []string{"links"},
+		},
+	}
+	aliases = alias.NewGenerator(aliasConfig)
+	policy  = alias.NewPolicy(aliasConfig.Policy)
+)
+
+func TestIngestHandler(t *testing.T) {
+	csvFile := strings.Join([]string{
+		"alias,url,clicks,tags",
+		"fresh,https://example.com/fresh,5,imported",
+		"mine,https://example.com/mine-new,0,",
+		"theirs,https://example.com/theirs-new,0,",
+		",https://example.com/generated,0,",
+		"links,https://example.com/reserved,0,",
+		"bad,not a url,0,",
+	}, "\n")
+
+	tests := []struct {
+		name        string
+		query       string
+		body        string
+		respStatus  int
+		respCode    string
+		saved       int
+		overwritten int
+		skipped     int
+		renamed     []Renamed
+		failed      []string
+		mine        string
+	}{
+		{
+			name:       "Skip",
+			body:       csvFile,
+			respStatus: http.StatusOK,
+			saved:      2,
+			skipped:    2,
+			failed:     []string{"5:" + response.CodeValidationFailed, "6:" + response.CodeValidationFailed},
+			mine:       "https://example.com/mine",
+		},
+		{
+			name:        "Overwrite",
+			query:       "conflict=overwrite",
+			body:        csvFile,
+			respStatus:  http.StatusOK,
+			saved:       2,
+			overwritten: 1,
+			failed: []string{
+				"3:" + response.CodeForbidden,
+				"5:" + response.CodeValidationFailed,
+				"6:" + response.CodeValidationFailed,
+			},
+			mine: "https://example.com/mine-new",
+		},
+		{
+			name:       "Rename",
+			query:      "conflict=rename",
+			body:       csvFile,
+			respStatus: http.StatusOK,
+			saved:      4,
+			renamed:    []Renamed{{Alias: "mine", NewAlias: "mine-2"}, {Alias: "theirs", NewAlias: "theirs-2"}},
+			failed:     []string{"5:" + response.CodeValidationFailed, "6:" + response.CodeValidationFailed},
+			mine:       "https://example.com/mine",
+		},
+		{
+			name:  "JSON Lines",
+			query: "format=jsonl",
+			body: `{"alias": "fresh", "url": "https://example.com/fresh"}
+{"alias": 
+{"alias": "mine", "url": "https://example.com/mine-new"}`,
+			respStatus: http.StatusOK,
+			saved:      1,
+			skipped:    1,
+			failed:     []string{"2:" + response.CodeInvalidRequest},
+			mine:       "https://example.com/mine",
+		},
+		{
+			name:       "Missing url column",
+			body:       "alias\nfresh",
+			respStatus: http.StatusBadRequest,
+			respCode:   response.CodeInvalidRequest,
+		},
+		{
+			name:       "Unknown conflict policy",
+			query:      "conflict=merge",
+			respStatus: http.StatusBadRequest,
+			respCode:   response.CodeValidationFailed,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			store := memory.New()
+			_, err := store.SaveURL("https://example.com/mine", "mine", testUserId, storage.URLOptions{})
+			require.NoError(t, err)
+			_, err = store.SaveURL("https://example.com/theirs", "theirs", otherUserId, storage.URLOptions{})
+			require.NoError(t, err)
+
+			handler := New(handlers.NewDiscardLogger(), store, aliases, policy, aliasConfig.Attempts)
+
+			req, err := http.NewRequest(http.MethodPost, "/links/import?"+tc.query, strings.NewReader(tc.body))
+			require.NoError(t, err)
+
+			req = req.WithContext(auth.WithUserID(req.Context(), testUserId))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respStatus, rr.Code)
+
+			var resp Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respCode, resp.Code)
+			if tc.respCode != "" {
+				return
+			}
+
+			require.Equal(t, tc.saved, resp.Saved)
+			require.Equal(t, tc.overwritten, resp.Overwritten)
+			require.Equal(t, tc.skipped, resp.Skipped)
+			require.Equal(t, tc.renamed, resp.Renamed)
+
+			var failed []string
+			for _, f := range resp.Failed {
+				failed = append(failed, fmt.Sprintf("%d:%s", f.Record, f.Code))
+			}
+			require.Equal(t, tc.failed, failed)
+
+			got, err := store.GetURL("mine")
+			require.NoError(t, err)
+			require.Equal(t, tc.mine, got)
+
+			got, err = store.GetURL("theirs")
+			require.NoError(t, err)
+			require.Equal(t, "https://example.com/theirs", got)
+
+			got, err = store.GetURL("fresh")
+			require.NoError(t, err)
+			require.Equal(t, "https://example.com/fresh", got)
+		})
+	}
+}
+
+func TestIngestHandler_Options(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		body   string
+		failed []string
+	}{
+		{
+			name: "CSV",
+			body: "alias,url,redirect_type,query_passthrough,path_passthrough\n" +
+				"moved,https://example.com/moved,301,append,true\n" +
+				"bad,https://example.com/bad,303,,",
+			failed: []string{"2:" + response.CodeValidationFailed},
+		},
+		{
+			name:  "JSON Lines",
+			query: "format=jsonl",
+			body: `{"alias": "moved", "url": "https://example.com/moved", "redirect_type": 301, "query_passthrough": "append", "path_passthrough": true}
+{"alias": "bad", "url": "https://example.com/bad", "redirect_type": 303}`,
+			failed: []string{"2:" + response.CodeValidationFailed},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			store := memory.New()
+			handler := New(handlers.NewDiscardLogger(), store, aliases, policy, aliasConfig.Attempts)
+
+			req, err := http.NewRequest(http.MethodPost, "/links/import?"+tc.query, strings.NewReader(tc.body))
+			require.NoError(t, err)
+
+			req = req.WithContext(auth.WithUserID(req.Context(), testUserId))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, 1, resp.Saved)
+
+			var failed []string
+			for _, f := range resp.Failed {
+				failed = append(failed, fmt.Sprintf("%d:%s", f.Record, f.Code))
+			}
+			require.Equal(t, tc.failed, failed)
+
+			target, err := store.HitURL("moved", false)
+			require.NoError(t, err)
+			require.Equal(t, 301, target.RedirectType)
+			require.Equal(t, storage.Passthrough{QueryPassthrough: storage.QueryAppend, PathPassthrough: true}, target.Passthrough)
+		})
+	}
+}
+
+func TestIngestHandler_OverwriteReplaces(t *testing.T) {
+	store := memory.New()
+	_, err := store.SaveURL("https://example.com/mine", "mine", testUserId, storage.URLOptions{
+		MaxClicks:    5,
+		Tags:         []string{"old"},
+		RedirectType: 301,
+	})
+	require.NoError(t, err)
+
+	handler := New(handlers.NewDiscardLogger(), store, aliases, policy, aliasConfig.Attempts)
+
+	req, err := http.NewRequest(http.MethodPost, "/links/import?conflict=overwrite",
+		strings.NewReader("alias,url,tags\nmine,https://example.com/mine-new,new"))
+	require.NoError(t, err)
+
+	req = req.WithContext(auth.WithUserID(req.Context(), testUserId))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp Response
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.Equal(t, 1, resp.Overwritten)
+
+	// Settings the record leaves out are reset, not kept.
+	urls, err := store.ListURLs(testUserId, storage.URLQuery{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	require.Equal(t, "https://example.com/mine-new", urls[0].URL)
+	require.Zero(t, urls[0].MaxClicks)
+	require.Zero(t, urls[0].RedirectType)
+	require.Equal(t, []string{"new"}, urls[0].Tags)
+}
+
+func TestIngestHandler_StorageError(t *testing.T) {
+	urlImporterMock := mocks.NewURLImporter(t)
+	urlImporterMock.On("SaveURL", "https://example.com", "fresh", testUserId, mock.AnythingOfType("storage.URLOptions")).
+		Return(int64(0), errors.New("unexpected error")).
+		Once()
+
+	handler := New(handlers.NewDiscardLogger(), urlImporterMock, aliases, policy, aliasConfig.Attempts)
+
+	req, err := http.NewRequest(http.MethodPost, "/links/import", strings.NewReader("alias,url\nfresh,https://example.com"))
+	require.NoError(t, err)
+
+	req = req.WithContext(auth.WithUserID(req.Context(), testUserId))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp Response
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+	require.Zero(t, resp.Saved)
+	require.Len(t, resp.Failed, 1)
+	require.Equal(t, response.CodeInternal, resp.Failed[0].Code)
+	require.Equal(t, "fresh", resp.Failed[0].Alias)
+}
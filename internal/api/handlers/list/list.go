@@ -0,0 +1,216 @@
+package list
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"url-shortener/internal/api/middleware/auth"
+	"url-shortener/internal/api/response"
+	"url-shortener/internal/lib/logger"
+	"url-shortener/internal/lib/validation"
+	"url-shortener/internal/storage"
+)
+
+const defaultLimit = 20
+
+// Query holds the query string parameters of a listing.
+type Query struct {
+	Search string `json:"q" validate:"max=256"`
+	Tag    string `json:"tag" validate:"max=32"`
+	Expiry string `json:"expiry" validate:"omitempty,oneof=expired expiring permanent"`
+	Sort   string `json:"sort" validate:"omitempty,oneof=created clicks"`
+	Limit  int    `json:"limit" validate:"min=1,max=100"`
+	// Cursor is the next_cursor of the previous page.
+	Cursor string `json:"cursor"`
+}
+
+type Response struct {
+	response.Response
+	Links []Link `json:"links"`
+	// NextCursor is empty on the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type Link struct {
+	Alias     string     `json:"alias"`
+	URL       string     `json:"url"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Clicks    int64      `json:"clicks"`
+	MaxClicks int64      `json:"max_clicks,omitempty"`
+	Tags      []string   `json:"tags,omitempty"`
+
+	RedirectType     int    `json:"redirect_type,omitempty"`
+	QueryPassthrough string `json:"query_passthrough,omitempty"`
+	PathPassthrough  bool   `json:"path_passthrough,omitempty"`
+}
+
+// cursor is the opaque position behind next_cursor.
+type cursor struct {
+	ID        int64      `json:"id"`
+	Clicks    int64      `json:"clicks,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+var (
+	errInvalidLimit = validation.FieldError{
+		Field:   "limit",
+		Rule:    "number",
+		Message: "limit must be a number",
+	}
+	errInvalidCursor = validation.FieldError{
+		Field:   "cursor",
+		Rule:    "cursor",
+		Message: "cursor is invalid",
+	}
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.43.2 --name=URLLister
+type URLLister interface {
+	ListURLs(userId int64, q storage.URLQuery) ([]storage.URLInfo, error)
+}
+
+func New(log *logger.Logger, urlLister URLLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.url.list.New"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userId, ok := auth.UserID(r.Context())
+		if !ok {
+			log.Error("user id is missing from context")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error(response.CodeUnauthorized, "unauthorized"))
+			return
+		}
+
+		params := r.URL.Query()
+		query := Query{
+			Search: params.Get("q"),
+			Tag:    params.Get("tag"),
+			Expiry: params.Get("expiry"),
+			Sort:   params.Get("sort"),
+			Limit:  defaultLimit,
+			Cursor: params.Get("cursor"),
+		}
+
+		if limit := params.Get("limit"); limit != "" {
+			n, err := strconv.Atoi(limit)
+			if err != nil {
+				log.Info("invalid limit", slog.String("limit", limit))
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, response.ValidationError(errInvalidLimit))
+				return
+			}
+			query.Limit = n
+		}
+
+		if errs := validation.Struct(query); errs != nil {
+			log.Info("invalid query", slog.Any("errors", errs))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.ValidationError(errs...))
+			return
+		}
+
+		q := storage.URLQuery{
+			Search: query.Search,
+			Tag:    query.Tag,
+			Expiry: query.Expiry,
+			Sort:   query.Sort,
+			// One more link than asked for tells whether another page follows.
+			Limit: query.Limit + 1,
+		}
+
+		if query.Cursor != "" {
+			after, err := decodeCursor(query.Cursor)
+			if err != nil {
+				log.Info("invalid cursor", slog.String("error", err.Error()))
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, response.ValidationError(errInvalidCursor))
+				return
+			}
+			q.After = &after
+		}
+
+		urls, err := urlLister.ListURLs(userId, q)
+		if err != nil {
+			log.Error("failed to list urls", slog.String("error", err.Error()))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error(response.CodeInternal, "internal error"))
+			return
+		}
+
+		resp := Response{
+			Response: response.OK(),
+			Links:    make([]Link, 0, min(len(urls), query.Limit)),
+		}
+
+		if len(urls) > query.Limit {
+			urls = urls[:query.Limit]
+			resp.NextCursor = encodeCursor(urls[len(urls)-1].Cursor())
+		}
+
+		for _, u := range urls {
+			link := Link{
+				Alias:     u.Alias,
+				URL:       u.URL,
+				Clicks:    u.Clicks,
+				MaxClicks: u.MaxClicks,
+				Tags:      u.Tags,
+
+				RedirectType:     u.RedirectType,
+				QueryPassthrough: u.QueryPassthrough,
+				PathPassthrough:  u.PathPassthrough,
+			}
+			if !u.CreatedAt.IsZero() {
+				createdAt := u.CreatedAt.UTC()
+				link.CreatedAt = &createdAt
+			}
+			if !u.ExpiresAt.IsZero() {
+				expiresAt := u.ExpiresAt.UTC()
+				link.ExpiresAt = &expiresAt
+			}
+			resp.Links = append(resp.Links, link)
+		}
+
+		render.JSON(w, r, resp)
+	}
+}
+
+func encodeCursor(c storage.URLCursor) string {
+	res := cursor{ID: c.ID, Clicks: c.Clicks}
+	if !c.CreatedAt.IsZero() {
+		res.CreatedAt = &c.CreatedAt
+	}
+
+	b, _ := json.Marshal(res)
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (storage.URLCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return storage.URLCursor{}, err
+	}
+
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return storage.URLCursor{}, err
+	}
+
+	res := storage.URLCursor{ID: c.ID, Clicks: c.Clicks}
+	if c.CreatedAt != nil {
+		res.CreatedAt = *c.CreatedAt
+	}
+
+	return res, nil
+}
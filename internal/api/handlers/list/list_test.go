@@ -0,0 +1,232 @@
+package list
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"url-shortener/internal/api/handlers/list/mocks"
+	"url-shortener/internal/api/middleware/auth"
+	"url-shortener/internal/api/response"
+	"url-shortener/internal/lib/logger/handlers"
+	"url-shortener/internal/storage"
+)
+
+const testUserId int64 = 1
+
+func TestListHandler(t *testing.T) {
+	createdAt := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	urls := []storage.URLInfo{
+		{ID: 3, Alias: "third", URL: "https://example.com/3", CreatedAt: createdAt, Clicks: 5, Tags: []string{"work"}},
+		{ID: 2, Alias: "second", URL: "https://example.com/2", Clicks: 2},
+		{ID: 1, Alias: "first", URL: "https://example.com/1"},
+	}
+
+	tests := []struct {
+		name        string
+		query       string
+		storeQuery  storage.URLQuery
+		urls        []storage.URLInfo
+		respAliases []string
+		respCursor  bool
+		respError   string
+		respCode    string
+		respStatus  int
+		respField   string
+		mockError   error
+	}{
+		{
+			name:        "Success",
+			storeQuery:  storage.URLQuery{Limit: defaultLimit + 1},
+			urls:        urls,
+			respAliases: []string{"third", "second", "first"},
+			respStatus:  http.StatusOK,
+		},
+		{
+			name:        "Empty",
+			storeQuery:  storage.URLQuery{Limit: defaultLimit + 1},
+			respAliases: []string{},
+			respStatus:  http.StatusOK,
+		},
+		{
+			name:        "Filters",
+			query:       "q=exa&tag=work&expiry=permanent&sort=clicks",
+			storeQuery:  storage.URLQuery{Search: "exa", Tag: "work", Expiry: storage.ExpiryPermanent, Sort: storage.SortClicks, Limit: defaultLimit + 1},
+			urls:        urls[:1],
+			respAliases: []string{"third"},
+			respStatus:  http.StatusOK,
+		},
+		{
+			name:        "More pages",
+			query:       "limit=2",
+			storeQuery:  storage.URLQuery{Limit: 3},
+			urls:        urls,
+			respAliases: []string{"third", "second"},
+			respCursor:  true,
+			respStatus:  http.StatusOK,
+		},
+		{
+			name:        "With cursor",
+			query:       "limit=2&sort=clicks&cursor=" + encodeCursor(storage.URLCursor{ID: 2, Clicks: 2}),
+			storeQuery:  storage.URLQuery{Sort: storage.SortClicks, After: &storage.URLCursor{ID: 2, Clicks: 2}, Limit: 3},
+			urls:        urls[2:],
+			respAliases: []string{"first"},
+			respStatus:  http.StatusOK,
+		},
+		{
+			name:        "With creation time cursor",
+			query:       "limit=2&cursor=" + encodeCursor(storage.URLCursor{ID: 3, CreatedAt: createdAt}),
+			storeQuery:  storage.URLQuery{After: &storage.URLCursor{ID: 3, CreatedAt: createdAt}, Limit: 3},
+			urls:        urls[1:],
+			respAliases: []string{"second", "first"},
+			respStatus:  http.StatusOK,
+		},
+		{
+			name:       "Invalid sort",
+			query:      "sort=alias",
+			respError:  "validation failed",
+			respCode:   response.CodeValidationFailed,
+			respStatus: http.StatusBadRequest,
+			respField:  "sort",
+		},
+		{
+			name:       "Invalid expiry",
+			query:      "expiry=soon",
+			respError:  "validation failed",
+			respCode:   response.CodeValidationFailed,
+			respStatus: http.StatusBadRequest,
+			respField:  "expiry",
+		},
+		{
+			name:       "Limit not a number",
+			query:      "limit=ten",
+			respError:  "validation failed",
+			respCode:   response.CodeValidationFailed,
+			respStatus: http.StatusBadRequest,
+			respField:  "limit",
+		},
+		{
+			name:       "Limit too large",
+			query:      "limit=1000",
+			respError:  "validation failed",
+			respCode:   response.CodeValidationFailed,
+			respStatus: http.StatusBadRequest,
+			respField:  "limit",
+		},
+		{
+			name:       "Invalid cursor",
+			query:      "cursor=%21%21",
+			respError:  "validation failed",
+			respCode:   response.CodeValidationFailed,
+			respStatus: http.StatusBadRequest,
+			respField:  "cursor",
+		},
+		{
+			name:       "Storage error",
+			storeQuery: storage.URLQuery{Limit: defaultLimit + 1},
+			respError:  "internal error",
+			respCode:   response.CodeInternal,
+			respStatus: http.StatusInternalServerError,
+			mockError:  errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlListerMock := mocks.NewURLLister(t)
+
+			if tc.respField == "" {
+				urlListerMock.On("ListURLs", testUserId, tc.storeQuery).
+					Return(tc.urls, tc.mockError).
+					Once()
+			}
+
+			handler := New(handlers.NewDiscardLogger(), urlListerMock)
+
+			req, err := http.NewRequest(http.MethodGet, "/links?"+tc.query, nil)
+			require.NoError(t, err)
+
+			req = req.WithContext(auth.WithUserID(req.Context(), testUserId))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respStatus, rr.Code)
+
+			var resp Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.respCode, resp.Code)
+
+			if tc.respField != "" {
+				require.Len(t, resp.Errors, 1)
+				require.Equal(t, tc.respField, resp.Errors[0].Field)
+			}
+			if tc.respError != "" {
+				return
+			}
+
+			aliases := []string{}
+			for _, l := range resp.Links {
+				aliases = append(aliases, l.Alias)
+			}
+			require.Equal(t, tc.respAliases, aliases)
+
+			require.Equal(t, tc.respCursor, resp.NextCursor != "")
+			if tc.respCursor {
+				after, err := decodeCursor(resp.NextCursor)
+				require.NoError(t, err)
+				require.Equal(t, tc.urls[len(resp.Links)-1].Cursor(), after)
+			}
+		})
+	}
+}
+
+func TestListHandler_Link(t *testing.T) {
+	createdAt := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	expiresAt := createdAt.Add(time.Hour)
+
+	urlListerMock := mocks.NewURLLister(t)
+	urlListerMock.On("ListURLs", testUserId, storage.URLQuery{Limit: defaultLimit + 1}).
+		Return([]storage.URLInfo{
+			{
+				ID: 1, Alias: "alias", URL: "https://example.com", CreatedAt: createdAt, ExpiresAt: expiresAt, Clicks: 2, MaxClicks: 5, Tags: []string{"work"},
+				RedirectType: 308,
+				Passthrough:  storage.Passthrough{QueryPassthrough: storage.QueryOverride, PathPassthrough: true},
+			},
+		}, nil).
+		Once()
+
+	req, err := http.NewRequest(http.MethodGet, "/links", nil)
+	require.NoError(t, err)
+
+	req = req.WithContext(auth.WithUserID(req.Context(), testUserId))
+
+	rr := httptest.NewRecorder()
+	New(handlers.NewDiscardLogger(), urlListerMock).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.JSONEq(t, `{
+		"status": "OK",
+		"links": [{
+			"alias": "alias",
+			"url": "https://example.com",
+			"created_at": "2024-03-10T12:00:00Z",
+			"expires_at": "2024-03-10T13:00:00Z",
+			"clicks": 2,
+			"max_clicks": 5,
+			"tags": ["work"],
+			"redirect_type": 308,
+			"query_passthrough": "override",
+			"path_passthrough": true
+		}]
+	}`, rr.Body.String())
+}
@@ -1,13 +1,17 @@
 package login
 
 import (
+	"errors"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"log/slog"
 	"net/http"
+	"time"
 	"url-shortener/internal/api/response"
 	"url-shortener/internal/lib/logger"
 	"url-shortener/internal/lib/security"
+	"url-shortener/internal/lib/validation"
+	"url-shortener/internal/storage"
 )
 
 type Request struct {
@@ -20,12 +24,13 @@ type Response struct {
 	Token string `json:"token"`
 }
 
+//go:generate go run github.com/vektra/mockery/v2@v2.43.2 --name=UserAuthenticator
 type UserAuthenticator interface {
 	AuthenticateUser(username string, password string) (int64, error)
-	CreateSession(userId int64, token string) (int64, error)
+	CreateSession(userId int64, token string, expiresAt time.Time) (int64, error)
 }
 
-func New(log *logger.Logger, authenticator UserAuthenticator) http.HandlerFunc {
+func New(log *logger.Logger, authenticator UserAuthenticator, sessionTTL time.Duration) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const fn = "handlers.url.login.New"
 
@@ -39,30 +44,48 @@ func New(log *logger.Logger, authenticator UserAuthenticator) http.HandlerFunc {
 		err := render.DecodeJSON(r.Body, &req)
 		if err != nil {
 			log.Error("failed to decode request body", slog.String("error", err.Error()))
-			render.JSON(w, r, response.Error("failed to decode request body"))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(response.CodeInvalidRequest, "failed to decode request body"))
 			return
 		}
 
-		if req.Username == "" || req.Password == "" {
-			http.Error(w, "Username and password are required", http.StatusBadRequest)
+		if errs := validation.Struct(req); errs != nil {
+			log.Info("invalid request", slog.Any("errors", errs))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.ValidationError(errs...))
 			return
 		}
 
 		userId, err := authenticator.AuthenticateUser(req.Username, req.Password)
-		if userId == 0 || err != nil {
+		if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
+			log.Error("failed to authenticate user", slog.String("error", err.Error()))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error(response.CodeInternal, "internal error"))
+			return
+		}
+		// An unknown user and a wrong password look the same to the client.
+		if userId == 0 {
 			log.Info("failed to login user", slog.String("username", req.Username))
-			render.JSON(w, r, response.Error("authentication failed"))
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error(response.CodeInvalidCredentials, "authentication failed"))
 			return
 		}
 
 		log.Info("user authenticated", slog.String("username", req.Username))
 
-		token := security.GenerateToken()
+		token, err := security.GenerateToken()
+		if err != nil {
+			log.Error("failed to generate token", slog.String("error", err.Error()))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error(response.CodeInternal, "failed to create session"))
+			return
+		}
 
-		_, err = authenticator.CreateSession(userId, token)
+		_, err = authenticator.CreateSession(userId, token, time.Now().Add(sessionTTL))
 		if err != nil {
-			log.Info("failed to create session", slog.String("username", req.Username))
-			render.JSON(w, r, response.Error("failed to create session"))
+			log.Error("failed to create session", slog.String("error", err.Error()))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error(response.CodeInternal, "failed to create session"))
 			return
 		}
 
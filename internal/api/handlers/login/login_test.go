@@ -0,0 +1,128 @@
+package login
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"url-shortener/internal/api/handlers/login/mocks"
+	"url-shortener/internal/api/response"
+	"url-shortener/internal/lib/logger/handlers"
+	"url-shortener/internal/storage"
+)
+
+func TestLoginHandler(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		userId       int64
+		authError    error
+		sessionError error
+		respError    string
+		respCode     string
+		respStatus   int
+		respField    string
+	}{
+		{
+			name:       "Success",
+			body:       `{"username": "user", "password": "password"}`,
+			userId:     1,
+			respStatus: http.StatusOK,
+		},
+		{
+			name:       "Malformed body",
+			body:       `{"username":`,
+			respError:  "failed to decode request body",
+			respCode:   response.CodeInvalidRequest,
+			respStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Missing password",
+			body:       `{"username": "user"}`,
+			respError:  "validation failed",
+			respCode:   response.CodeValidationFailed,
+			respStatus: http.StatusBadRequest,
+			respField:  "password",
+		},
+		{
+			name:       "Wrong password",
+			body:       `{"username": "user", "password": "wrong"}`,
+			respError:  "authentication failed",
+			respCode:   response.CodeInvalidCredentials,
+			respStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Unknown user",
+			body:       `{"username": "user", "password": "password"}`,
+			authError:  storage.ErrUserNotFound,
+			respError:  "authentication failed",
+			respCode:   response.CodeInvalidCredentials,
+			respStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Storage error",
+			body:       `{"username": "user", "password": "password"}`,
+			authError:  errors.New("unexpected error"),
+			respError:  "internal error",
+			respCode:   response.CodeInternal,
+			respStatus: http.StatusInternalServerError,
+		},
+		{
+			name:         "Session error",
+			body:         `{"username": "user", "password": "password"}`,
+			userId:       1,
+			sessionError: errors.New("unexpected error"),
+			respError:    "failed to create session",
+			respCode:     response.CodeInternal,
+			respStatus:   http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			authenticatorMock := mocks.NewUserAuthenticator(t)
+
+			if tc.respCode != response.CodeInvalidRequest && tc.respCode != response.CodeValidationFailed {
+				authenticatorMock.On("AuthenticateUser", "user", mock.AnythingOfType("string")).
+					Return(tc.userId, tc.authError).
+					Once()
+			}
+			if tc.userId != 0 {
+				authenticatorMock.On("CreateSession", tc.userId, mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).
+					Return(int64(1), tc.sessionError).
+					Once()
+			}
+
+			handler := New(handlers.NewDiscardLogger(), authenticatorMock, time.Hour)
+
+			req, err := http.NewRequest(http.MethodPost, "/login", bytes.NewReader([]byte(tc.body)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respStatus, rr.Code)
+
+			var resp Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.respCode, resp.Code)
+			require.Equal(t, tc.respError == "", resp.Token != "")
+
+			if tc.respField != "" {
+				require.Len(t, resp.Errors, 1)
+				require.Equal(t, tc.respField, resp.Errors[0].Field)
+				require.Equal(t, "required", resp.Errors[0].Rule)
+			}
+		})
+	}
+}
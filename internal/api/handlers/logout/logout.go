@@ -0,0 +1,81 @@
+package logout
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/api/middleware/auth"
+	"url-shortener/internal/api/response"
+	"url-shortener/internal/lib/logger"
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.43.2 --name=SessionRevoker
+type SessionRevoker interface {
+	DeleteSession(token string) error
+	DeleteUserSessions(userId int64) error
+}
+
+// New revokes the session the request was authenticated with.
+func New(log *logger.Logger, revoker SessionRevoker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.url.logout.New"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		token, ok := auth.Token(r.Context())
+		if !ok {
+			log.Error("session token is missing from context")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error(response.CodeUnauthorized, "unauthorized"))
+			return
+		}
+
+		err := revoker.DeleteSession(token)
+		if err != nil {
+			log.Error("failed to delete session", slog.String("error", err.Error()))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error(response.CodeInternal, "failed to logout"))
+			return
+		}
+
+		log.Info("session revoked")
+
+		render.JSON(w, r, response.OK())
+	}
+}
+
+// NewAll revokes every session of the authenticated user.
+func NewAll(log *logger.Logger, revoker SessionRevoker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.url.logout.NewAll"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userId, ok := auth.UserID(r.Context())
+		if !ok {
+			log.Error("user id is missing from context")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error(response.CodeUnauthorized, "unauthorized"))
+			return
+		}
+
+		err := revoker.DeleteUserSessions(userId)
+		if err != nil {
+			log.Error("failed to delete sessions", slog.String("error", err.Error()))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error(response.CodeInternal, "failed to logout"))
+			return
+		}
+
+		log.Info("all sessions revoked", slog.Int64("user_id", userId))
+
+		render.JSON(w, r, response.OK())
+	}
+}
@@ -0,0 +1,126 @@
+package logout
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"url-shortener/internal/api/handlers/logout/mocks"
+	"url-shortener/internal/api/middleware/auth"
+	"url-shortener/internal/api/response"
+	"url-shortener/internal/lib/logger/handlers"
+)
+
+func TestLogoutHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      string
+		respError  string
+		respCode   string
+		respStatus int
+		mockError  error
+	}{
+		{
+			name:       "Success",
+			token:      "valid_token",
+			respStatus: http.StatusOK,
+		},
+		{
+			name:       "Failed to delete",
+			token:      "valid_token",
+			respError:  "failed to logout",
+			respCode:   response.CodeInternal,
+			respStatus: http.StatusInternalServerError,
+			mockError:  errors.New("internal error"),
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			revokerMock := mocks.NewSessionRevoker(t)
+
+			revokerMock.On("DeleteSession", tc.token).
+				Return(tc.mockError).
+				Once()
+
+			handler := New(handlers.NewDiscardLogger(), revokerMock)
+
+			req, err := http.NewRequest(http.MethodPost, "/logout", nil)
+			require.NoError(t, err)
+
+			req = req.WithContext(auth.WithToken(req.Context(), tc.token))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respStatus, rr.Code)
+
+			var resp response.Response
+
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.respCode, resp.Code)
+		})
+	}
+}
+
+func TestLogoutAllHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		userId     int64
+		respError  string
+		respCode   string
+		respStatus int
+		mockError  error
+	}{
+		{
+			name:       "Success",
+			userId:     1,
+			respStatus: http.StatusOK,
+		},
+		{
+			name:       "Failed to delete",
+			userId:     1,
+			respError:  "failed to logout",
+			respCode:   response.CodeInternal,
+			respStatus: http.StatusInternalServerError,
+			mockError:  errors.New("internal error"),
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			revokerMock := mocks.NewSessionRevoker(t)
+
+			revokerMock.On("DeleteUserSessions", tc.userId).
+				Return(tc.mockError).
+				Once()
+
+			handler := NewAll(handlers.NewDiscardLogger(), revokerMock)
+
+			req, err := http.NewRequest(http.MethodPost, "/logout-all", nil)
+			require.NoError(t, err)
+
+			req = req.WithContext(auth.WithUserID(req.Context(), tc.userId))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respStatus, rr.Code)
+
+			var resp response.Response
+
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.respCode, resp.Code)
+		})
+	}
+}
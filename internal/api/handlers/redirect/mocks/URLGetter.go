@@ -2,34 +2,38 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	mock "github.com/stretchr/testify/mock"
+
+	storage "url-shortener/internal/storage"
+)
 
 // URLGetter is an autogenerated mock type for the URLGetter type
 type URLGetter struct {
 	mock.Mock
 }
 
-// GetURL provides a mock function with given fields: alias
-func (_m *URLGetter) GetURL(alias string) (string, error) {
-	ret := _m.Called(alias)
+// HitURL provides a mock function with given fields: alias, subPath
+func (_m *URLGetter) HitURL(alias string, subPath bool) (storage.URLTarget, error) {
+	ret := _m.Called(alias, subPath)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetURL")
+		panic("no return value specified for HitURL")
 	}
 
-	var r0 string
+	var r0 storage.URLTarget
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string) (string, error)); ok {
-		return rf(alias)
+	if rf, ok := ret.Get(0).(func(string, bool) (storage.URLTarget, error)); ok {
+		return rf(alias, subPath)
 	}
-	if rf, ok := ret.Get(0).(func(string) string); ok {
-		r0 = rf(alias)
+	if rf, ok := ret.Get(0).(func(string, bool) storage.URLTarget); ok {
+		r0 = rf(alias, subPath)
 	} else {
-		r0 = ret.Get(0).(string)
+		r0 = ret.Get(0).(storage.URLTarget)
 	}
 
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(alias)
+	if rf, ok := ret.Get(1).(func(string, bool) error); ok {
+		r1 = rf(alias, subPath)
 	} else {
 		r1 = ret.Error(1)
 	}
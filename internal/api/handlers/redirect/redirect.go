@@ -2,11 +2,16 @@ package redirect
 
 import (
 	"errors"
+	"fmt"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
 	"url-shortener/internal/api/response"
 	"url-shortener/internal/lib/logger"
 	"url-shortener/internal/storage"
@@ -14,10 +19,28 @@ import (
 
 //go:generate go run github.com/vektra/mockery/v2@v2.43.2 --name=URLGetter
 type URLGetter interface {
-	GetURL(alias string) (string, error)
+	HitURL(alias string, subPath bool) (storage.URLTarget, error)
 }
 
-func New(log *logger.Logger, urlGetter URLGetter) http.HandlerFunc {
+//go:generate go run github.com/vektra/mockery/v2@v2.43.2 --name=ClickRecorder
+type ClickRecorder interface {
+	Record(alias string, r *http.Request)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.43.2 --name=RedirectCounter
+type RedirectCounter interface {
+	Redirected()
+	NotFound()
+}
+
+func New(
+	log *logger.Logger,
+	urlGetter URLGetter,
+	clickRecorder ClickRecorder,
+	redirectCounter RedirectCounter,
+	defaultType int,
+	cacheMaxAge time.Duration,
+) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const fn = "handlers.url.redirect.New"
 
@@ -29,25 +52,168 @@ func New(log *logger.Logger, urlGetter URLGetter) http.HandlerFunc {
 		alias := chi.URLParam(r, "alias")
 		if alias == "" {
 			log.Info("alias is empty")
-			render.JSON(w, r, response.Error("invalid request"))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(response.CodeInvalidRequest, "invalid request"))
 			return
 		}
 
-		resURL, err := urlGetter.GetURL(alias)
+		// Links without path passthrough do not answer below their alias.
+		subPath := strings.Contains(strings.TrimPrefix(r.URL.Path, "/"), "/")
+
+		target, err := urlGetter.HitURL(alias, subPath)
 		if errors.Is(err, storage.ErrURLNotFound) {
 			log.Info("url not found", "alias", alias)
+			redirectCounter.NotFound()
 			render.Status(r, http.StatusNotFound)
-			render.JSON(w, r, response.Error("not found"))
+			render.JSON(w, r, response.Error(response.CodeNotFound, "not found"))
+			return
+		}
+		if errors.Is(err, storage.ErrURLExpired) {
+			log.Info("url expired", "alias", alias)
+			render.Status(r, http.StatusGone)
+			render.JSON(w, r, response.Error(response.CodeLinkExpired, "link expired"))
+			return
+		}
+		if errors.Is(err, storage.ErrURLExhausted) {
+			log.Info("url click limit reached", "alias", alias)
+			render.Status(r, http.StatusGone)
+			render.JSON(w, r, response.Error(response.CodeLinkExhausted, "link click limit reached"))
 			return
 		}
 		if err != nil {
 			log.Error("failed to get url", slog.String("error", err.Error()))
-			render.JSON(w, r, response.Error("internal error"))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error(response.CodeInternal, "internal error"))
 			return
 		}
 
-		log.Info("got url", slog.String("url", resURL))
+		log.Info("got url", slog.String("url", target.URL))
+
+		clickRecorder.Record(alias, r)
+		redirectCounter.Redirected()
+
+		status := target.RedirectType
+		if status == 0 {
+			status = defaultType
+		}
+
+		dest, err := destination(target, r)
+		if err != nil {
+			log.Error("failed to pass request through", slog.String("error", err.Error()))
+			dest = target.URL
+		}
+
+		w.Header().Set("Cache-Control", cacheControl(status, target, cacheMaxAge, time.Now()))
+		http.Redirect(w, r, dest, status)
+	}
+}
+
+// destination applies the passthrough options of the link to its URL. Parts
+// of the request that the link does not pass through are dropped.
+func destination(target storage.URLTarget, r *http.Request) (string, error) {
+	query := r.URL.Query()
+	if target.QueryPassthrough == "" {
+		query = nil
+	}
+
+	var suffix string
+	if target.PathPassthrough {
+		suffix = pathSuffix(r.URL.EscapedPath())
+	}
+
+	if len(query) == 0 && suffix == "" {
+		return target.URL, nil
+	}
+
+	u, err := url.Parse(target.URL)
+	if err != nil {
+		return "", err
+	}
+
+	if suffix != "" {
+		u = u.JoinPath(suffix)
+	}
+	if len(query) > 0 {
+		u.RawQuery = mergeQuery(u.Query(), query, target.QueryPassthrough).Encode()
+	}
+
+	return u.String(), nil
+}
+
+// pathSuffix returns the part of an escaped request path below the alias,
+// cleaned so that it cannot climb above the destination's path. Dot segments
+// are resolved after unescaping, as clients and servers normalizing the
+// destination would, and a suffix hiding one behind an escaped slash is
+// dropped.
+func pathSuffix(escapedPath string) string {
+	_, suffix, _ := strings.Cut(strings.TrimPrefix(escapedPath, "/"), "/")
+
+	var segments []string
+	for _, segment := range strings.Split(suffix, "/") {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil {
+			return ""
+		}
+
+		switch {
+		case unescaped == "" || unescaped == ".":
+		case unescaped == "..":
+			if len(segments) > 0 {
+				segments = segments[:len(segments)-1]
+			}
+		case slices.ContainsFunc(strings.FieldsFunc(unescaped, isSlash), isDotSegment):
+			return ""
+		default:
+			segments = append(segments, segment)
+		}
+	}
+	if len(segments) == 0 {
+		return ""
+	}
+
+	cleaned := "/" + strings.Join(segments, "/")
+	if strings.HasSuffix(suffix, "/") {
+		cleaned += "/"
+	}
+
+	return cleaned
+}
+
+func isSlash(r rune) bool {
+	return r == '/' || r == '\\'
+}
+
+func isDotSegment(segment string) bool {
+	return segment == "." || segment == ".."
+}
+
+func mergeQuery(dst url.Values, src url.Values, policy string) url.Values {
+	for key, values := range src {
+		switch {
+		case !dst.Has(key), policy == storage.QueryOverride:
+			dst[key] = values
+		case policy == storage.QueryAppend:
+			dst[key] = append(dst[key], values...)
+		}
+	}
+
+	return dst
+}
 
-		http.Redirect(w, r, resURL, http.StatusFound)
+// cacheControl lets clients cache permanent redirects, but not past the
+// link's expiry. Temporary redirects, and those of links with a click limit,
+// are not cached, so that every click reaches the server and is counted.
+func cacheControl(status int, target storage.URLTarget, maxAge time.Duration, now time.Time) string {
+	if status != http.StatusMovedPermanently && status != http.StatusPermanentRedirect || target.MaxClicks > 0 {
+		return "no-store"
 	}
+
+	if !target.ExpiresAt.IsZero() {
+		maxAge = min(maxAge, target.ExpiresAt.Sub(now))
+	}
+	if maxAge < time.Second {
+		return "no-store"
+	}
+
+	return fmt.Sprintf("public, max-age=%d", int64(maxAge/time.Second))
 }
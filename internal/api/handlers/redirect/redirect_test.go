@@ -4,10 +4,12 @@ import (
 	"encoding/json"
 	"errors"
 	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 	"url-shortener/internal/api/handlers/redirect/mocks"
 	"url-shortener/internal/api/response"
 	"url-shortener/internal/lib/logger/handlers"
@@ -16,30 +18,91 @@ import (
 
 func TestRedirectHandler(t *testing.T) {
 	tests := []struct {
-		name      string
-		url       string
-		alias     string
-		mockError error
-		respError string
+		name         string
+		url          string
+		alias        string
+		redirectType int
+		passthrough  storage.Passthrough
+		suffix       string
+		location     string
+		mockError    error
+		respError    string
+		respCode     string
+		respStatus   int
 	}{
 		{
-			name:  "Valid alias",
-			url:   "https://example.com",
-			alias: "validAlias",
+			name:       "Valid alias",
+			url:        "https://example.com",
+			alias:      "validAlias",
+			respStatus: http.StatusFound,
+		},
+		{
+			name:         "Permanent redirect",
+			url:          "https://example.com",
+			alias:        "permanentAlias",
+			redirectType: http.StatusMovedPermanently,
+			respStatus:   http.StatusMovedPermanently,
+		},
+		{
+			name:         "Method preserving redirect",
+			url:          "https://example.com/api",
+			alias:        "apiAlias",
+			redirectType: http.StatusTemporaryRedirect,
+			respStatus:   http.StatusTemporaryRedirect,
+		},
+		{
+			name:        "Path below alias",
+			url:         "https://example.com/docs",
+			alias:       "docsAlias",
+			passthrough: storage.Passthrough{PathPassthrough: true},
+			suffix:      "/guide/intro",
+			location:    "https://example.com/docs/guide/intro",
+			respStatus:  http.StatusFound,
+		},
+		{
+			name:       "Sub-path without passthrough",
+			alias:      "plainAlias",
+			suffix:     "/anything",
+			mockError:  storage.ErrURLNotFound,
+			respError:  "not found",
+			respCode:   response.CodeNotFound,
+			respStatus: http.StatusNotFound,
 		},
 		{
-			name:      "Alias not found",
-			url:       "",
-			alias:     "notFoundAlias",
-			mockError: storage.ErrURLNotFound,
-			respError: "not found",
+			name:       "Alias not found",
+			url:        "",
+			alias:      "notFoundAlias",
+			mockError:  storage.ErrURLNotFound,
+			respError:  "not found",
+			respCode:   response.CodeNotFound,
+			respStatus: http.StatusNotFound,
 		},
 		{
-			name:      "Internal error",
-			url:       "",
-			alias:     "internalErrorAlias",
-			mockError: errors.New("internal error"),
-			respError: "internal error",
+			name:       "Expired alias",
+			url:        "",
+			alias:      "expiredAlias",
+			mockError:  storage.ErrURLExpired,
+			respError:  "link expired",
+			respCode:   response.CodeLinkExpired,
+			respStatus: http.StatusGone,
+		},
+		{
+			name:       "Click limit reached",
+			url:        "",
+			alias:      "exhaustedAlias",
+			mockError:  storage.ErrURLExhausted,
+			respError:  "link click limit reached",
+			respCode:   response.CodeLinkExhausted,
+			respStatus: http.StatusGone,
+		},
+		{
+			name:       "Internal error",
+			url:        "",
+			alias:      "internalErrorAlias",
+			mockError:  errors.New("internal error"),
+			respError:  "internal error",
+			respCode:   response.CodeInternal,
+			respStatus: http.StatusInternalServerError,
 		},
 	}
 
@@ -51,15 +114,39 @@ func TestRedirectHandler(t *testing.T) {
 			urlGetterMock := mocks.NewURLGetter(t)
 
 			if tc.alias != "" {
-				urlGetterMock.On("GetURL", tc.alias).Return(tc.url, tc.mockError)
+				target := storage.URLTarget{URL: tc.url, RedirectType: tc.redirectType, Passthrough: tc.passthrough}
+				urlGetterMock.On("HitURL", tc.alias, tc.suffix != "").Return(target, tc.mockError)
+			}
+
+			clickRecorderMock := mocks.NewClickRecorder(t)
+
+			if tc.mockError == nil {
+				clickRecorderMock.On("Record", tc.alias, mock.AnythingOfType("*http.Request")).Once()
 			}
 
-			handler := New(handlers.NewDiscardLogger(), urlGetterMock)
+			redirectCounterMock := mocks.NewRedirectCounter(t)
+
+			if tc.mockError == nil {
+				redirectCounterMock.On("Redirected").Once()
+			}
+			if errors.Is(tc.mockError, storage.ErrURLNotFound) {
+				redirectCounterMock.On("NotFound").Once()
+			}
+
+			handler := New(
+				handlers.NewDiscardLogger(),
+				urlGetterMock,
+				clickRecorderMock,
+				redirectCounterMock,
+				http.StatusFound,
+				time.Hour,
+			)
 
 			router := chi.NewRouter()
 			router.Get("/{alias}", handler)
+			router.Get("/{alias}/*", handler)
 
-			req, err := http.NewRequest(http.MethodGet, "/"+tc.alias, nil)
+			req, err := http.NewRequest(http.MethodGet, "/"+tc.alias+tc.suffix, nil)
 			require.NoError(t, err)
 
 			rr := httptest.NewRecorder()
@@ -72,14 +159,198 @@ func TestRedirectHandler(t *testing.T) {
 			if tc.url == "" {
 				require.NoError(t, json.Unmarshal([]byte(body), &resp))
 
+				require.Equal(t, tc.respStatus, rr.Code)
+
 				if tc.respError != "" {
 					require.Equal(t, tc.respError, resp.Error)
+					require.Equal(t, tc.respCode, resp.Code)
 				} else {
 					require.Empty(t, resp.Error)
 				}
 			} else {
-				require.Equal(t, tc.url, rr.Header().Get("Location"))
+				require.Equal(t, tc.respStatus, rr.Code)
+				location := tc.location
+				if location == "" {
+					location = tc.url
+				}
+				require.Equal(t, location, rr.Header().Get("Location"))
+				if tc.respStatus == http.StatusMovedPermanently {
+					require.Equal(t, "public, max-age=3600", rr.Header().Get("Cache-Control"))
+				} else {
+					require.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+				}
 			}
 		})
 	}
 }
+
+func TestCacheControl(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		status    int
+		expiresAt time.Time
+		maxClicks int64
+		want      string
+	}{
+		{name: "Found", status: http.StatusFound, want: "no-store"},
+		{name: "Temporary redirect", status: http.StatusTemporaryRedirect, want: "no-store"},
+		{name: "Moved permanently", status: http.StatusMovedPermanently, want: "public, max-age=86400"},
+		{name: "Permanent redirect", status: http.StatusPermanentRedirect, want: "public, max-age=86400"},
+		{
+			name:      "Expires before max age",
+			status:    http.StatusPermanentRedirect,
+			expiresAt: now.Add(90 * time.Second),
+			want:      "public, max-age=90",
+		},
+		{
+			name:      "Expires after max age",
+			status:    http.StatusMovedPermanently,
+			expiresAt: now.Add(72 * time.Hour),
+			want:      "public, max-age=86400",
+		},
+		{
+			name:      "Click limited",
+			status:    http.StatusPermanentRedirect,
+			maxClicks: 10,
+			want:      "no-store",
+		},
+		{
+			name:      "Expires within a second",
+			status:    http.StatusMovedPermanently,
+			expiresAt: now.Add(time.Millisecond),
+			want:      "no-store",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.want, cacheControl(tc.status, storage.URLTarget{ExpiresAt: tc.expiresAt, MaxClicks: tc.maxClicks}, 24*time.Hour, now))
+		})
+	}
+}
+
+func TestDestination(t *testing.T) {
+	tests := []struct {
+		name        string
+		url         string
+		passthrough storage.Passthrough
+		request     string
+		want        string
+	}{
+		{
+			name:    "Passthrough off",
+			url:     "https://example.com/docs?lang=en",
+			request: "/docs/guide?utm_source=x",
+			want:    "https://example.com/docs?lang=en",
+		},
+		{
+			name:        "Query added",
+			url:         "https://example.com/docs",
+			passthrough: storage.Passthrough{QueryPassthrough: storage.QueryKeep},
+			request:     "/docs?utm_source=x&utm_medium=y",
+			want:        "https://example.com/docs?utm_medium=y&utm_source=x",
+		},
+		{
+			name:        "Query conflict kept",
+			url:         "https://example.com/docs?lang=en",
+			passthrough: storage.Passthrough{QueryPassthrough: storage.QueryKeep},
+			request:     "/docs?lang=de&utm_source=x",
+			want:        "https://example.com/docs?lang=en&utm_source=x",
+		},
+		{
+			name:        "Query conflict overridden",
+			url:         "https://example.com/docs?lang=en",
+			passthrough: storage.Passthrough{QueryPassthrough: storage.QueryOverride},
+			request:     "/docs?lang=de",
+			want:        "https://example.com/docs?lang=de",
+		},
+		{
+			name:        "Query conflict appended",
+			url:         "https://example.com/docs?lang=en",
+			passthrough: storage.Passthrough{QueryPassthrough: storage.QueryAppend},
+			request:     "/docs?lang=de",
+			want:        "https://example.com/docs?lang=en&lang=de",
+		},
+		{
+			name:        "Empty query leaves the destination alone",
+			url:         "https://example.com/docs?b=2&a=1",
+			passthrough: storage.Passthrough{QueryPassthrough: storage.QueryAppend},
+			request:     "/docs",
+			want:        "https://example.com/docs?b=2&a=1",
+		},
+		{
+			name:        "Path appended",
+			url:         "https://example.com/docs/?lang=en",
+			passthrough: storage.Passthrough{PathPassthrough: true},
+			request:     "/docs/guide/intro.html?utm_source=x",
+			want:        "https://example.com/docs/guide/intro.html?lang=en",
+		},
+		{
+			name:        "Trailing slash kept",
+			url:         "https://example.com/docs",
+			passthrough: storage.Passthrough{PathPassthrough: true},
+			request:     "/docs/guide/",
+			want:        "https://example.com/docs/guide/",
+		},
+		{
+			name:        "Escaped path kept",
+			url:         "https://example.com/docs",
+			passthrough: storage.Passthrough{PathPassthrough: true},
+			request:     "/docs/a%2Fb/c%20d",
+			want:        "https://example.com/docs/a%2Fb/c%20d",
+		},
+		{
+			name:        "Path cannot climb above the destination",
+			url:         "https://example.com/docs",
+			passthrough: storage.Passthrough{PathPassthrough: true},
+			request:     "/docs/../../admin",
+			want:        "https://example.com/docs/admin",
+		},
+		{
+			name:        "Escaped dot segments resolved",
+			url:         "https://example.com/docs",
+			passthrough: storage.Passthrough{PathPassthrough: true},
+			request:     "/docs/guide/%2e%2e/%2E%2e/%2e%2e/x",
+			want:        "https://example.com/docs/x",
+		},
+		{
+			name:        "Dot segment behind escaped slash dropped",
+			url:         "https://example.com/docs",
+			passthrough: storage.Passthrough{PathPassthrough: true},
+			request:     "/docs/a/..%2F..%2Fadmin",
+			want:        "https://example.com/docs",
+		},
+		{
+			name:        "Dot segment behind escaped backslash dropped",
+			url:         "https://example.com/docs",
+			passthrough: storage.Passthrough{PathPassthrough: true},
+			request:     "/docs/..%5Cadmin",
+			want:        "https://example.com/docs",
+		},
+		{
+			name:        "Path and query",
+			url:         "https://example.com/docs",
+			passthrough: storage.Passthrough{QueryPassthrough: storage.QueryKeep, PathPassthrough: true},
+			request:     "/docs/guide?utm_source=x",
+			want:        "https://example.com/docs/guide?utm_source=x",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, tc.request, nil)
+
+			got, err := destination(storage.URLTarget{URL: tc.url, Passthrough: tc.passthrough}, req)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"url-shortener/internal/api/response"
 	"url-shortener/internal/lib/logger"
+	"url-shortener/internal/lib/validation"
 	"url-shortener/internal/storage"
 )
 
@@ -16,6 +17,7 @@ type Request struct {
 	Password string `json:"password" validate:"required"`
 }
 
+//go:generate go run github.com/vektra/mockery/v2@v2.43.2 --name=UserCreator
 type UserCreator interface {
 	CreateUser(username string, password string) (int64, error)
 }
@@ -34,27 +36,30 @@ func New(log *logger.Logger, userCreator UserCreator) http.HandlerFunc {
 		err := render.DecodeJSON(r.Body, &req)
 		if err != nil {
 			log.Error("failed to decode request body", slog.String("error", err.Error()))
-			render.JSON(w, r, response.Error("failed to decode request body"))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(response.CodeInvalidRequest, "failed to decode request body"))
 			return
 		}
 
-		if req.Username == "" || req.Password == "" {
-			http.Error(w, "Username and password are required", http.StatusBadRequest)
+		if errs := validation.Struct(req); errs != nil {
+			log.Info("invalid request", slog.Any("errors", errs))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.ValidationError(errs...))
 			return
 		}
 
 		id, err := userCreator.CreateUser(req.Username, req.Password)
-
+		if errors.Is(err, storage.ErrUserExists) {
+			log.Info("username already exists", slog.String("username", req.Username))
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, response.Error(response.CodeUsernameTaken, "username already exists"))
+			return
+		}
 		if err != nil {
-			if errors.Is(err, storage.ErrUserExists) {
-				log.Info("username already exists", slog.String("username", req.Username))
-				render.JSON(w, r, response.Error("username already exists"))
-				return
-			} else {
-				log.Info("failed to create user", slog.String("username", req.Username))
-				render.JSON(w, r, response.Error("failed to create user"))
-				return
-			}
+			log.Error("failed to create user", slog.String("error", err.Error()))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error(response.CodeInternal, "failed to create user"))
+			return
 		}
 
 		log.Info("user created", slog.Int64("id", id))
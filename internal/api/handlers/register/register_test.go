@@ -0,0 +1,101 @@
+package register
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"url-shortener/internal/api/handlers/register/mocks"
+	"url-shortener/internal/api/response"
+	"url-shortener/internal/lib/logger/handlers"
+	"url-shortener/internal/storage"
+)
+
+func TestRegisterHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		mockError  error
+		respError  string
+		respCode   string
+		respStatus int
+		respField  string
+	}{
+		{
+			name:       "Success",
+			body:       `{"username": "user", "password": "password"}`,
+			respStatus: http.StatusOK,
+		},
+		{
+			name:       "Malformed body",
+			body:       `not json`,
+			respError:  "failed to decode request body",
+			respCode:   response.CodeInvalidRequest,
+			respStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Missing username",
+			body:       `{"password": "password"}`,
+			respError:  "validation failed",
+			respCode:   response.CodeValidationFailed,
+			respStatus: http.StatusBadRequest,
+			respField:  "username",
+		},
+		{
+			name:       "Username taken",
+			body:       `{"username": "user", "password": "password"}`,
+			mockError:  storage.ErrUserExists,
+			respError:  "username already exists",
+			respCode:   response.CodeUsernameTaken,
+			respStatus: http.StatusConflict,
+		},
+		{
+			name:       "Storage error",
+			body:       `{"username": "user", "password": "password"}`,
+			mockError:  errors.New("unexpected error"),
+			respError:  "failed to create user",
+			respCode:   response.CodeInternal,
+			respStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			userCreatorMock := mocks.NewUserCreator(t)
+
+			if tc.respError == "" || tc.mockError != nil {
+				userCreatorMock.On("CreateUser", "user", "password").
+					Return(int64(1), tc.mockError).
+					Once()
+			}
+
+			handler := New(handlers.NewDiscardLogger(), userCreatorMock)
+
+			req, err := http.NewRequest(http.MethodPost, "/register", bytes.NewReader([]byte(tc.body)))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respStatus, rr.Code)
+
+			var resp response.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.respCode, resp.Code)
+
+			if tc.respField != "" {
+				require.Len(t, resp.Errors, 1)
+				require.Equal(t, tc.respField, resp.Errors[0].Field)
+				require.Equal(t, "required", resp.Errors[0].Rule)
+			}
+		})
+	}
+}
@@ -2,16 +2,48 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	mock "github.com/stretchr/testify/mock"
+
+	storage "url-shortener/internal/storage"
+)
 
 // URLSaver is an autogenerated mock type for the URLSaver type
 type URLSaver struct {
 	mock.Mock
 }
 
-// SaveURL provides a mock function with given fields: urlToSave, alias
-func (_m *URLSaver) SaveURL(urlToSave string, alias string) (int64, error) {
-	ret := _m.Called(urlToSave, alias)
+// NextURLID provides a mock function with no fields
+func (_m *URLSaver) NextURLID() (int64, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for NextURLID")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func() (int64, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() int64); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SaveURL provides a mock function with given fields: urlToSave, alias, userId, opts
+func (_m *URLSaver) SaveURL(urlToSave string, alias string, userId int64, opts storage.URLOptions) (int64, error) {
+	ret := _m.Called(urlToSave, alias, userId, opts)
 
 	if len(ret) == 0 {
 		panic("no return value specified for SaveURL")
@@ -19,17 +51,17 @@ func (_m *URLSaver) SaveURL(urlToSave string, alias string) (int64, error) {
 
 	var r0 int64
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string, string) (int64, error)); ok {
-		return rf(urlToSave, alias)
+	if rf, ok := ret.Get(0).(func(string, string, int64, storage.URLOptions) (int64, error)); ok {
+		return rf(urlToSave, alias, userId, opts)
 	}
-	if rf, ok := ret.Get(0).(func(string, string) int64); ok {
-		r0 = rf(urlToSave, alias)
+	if rf, ok := ret.Get(0).(func(string, string, int64, storage.URLOptions) int64); ok {
+		r0 = rf(urlToSave, alias, userId, opts)
 	} else {
 		r0 = ret.Get(0).(int64)
 	}
 
-	if rf, ok := ret.Get(1).(func(string, string) error); ok {
-		r1 = rf(urlToSave, alias)
+	if rf, ok := ret.Get(1).(func(string, string, int64, storage.URLOptions) error); ok {
+		r1 = rf(urlToSave, alias, userId, opts)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -2,36 +2,251 @@ package save
 
 import (
 	"errors"
+	"fmt"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
-	"github.com/go-playground/validator/v10"
 	"log/slog"
 	"net/http"
+	"time"
+	"url-shortener/internal/api/middleware/auth"
 	"url-shortener/internal/api/response"
 	"url-shortener/internal/lib/logger"
-	"url-shortener/internal/lib/random"
+	"url-shortener/internal/lib/validation"
 	"url-shortener/internal/storage"
 )
 
 type Request struct {
 	URL   string `json:"url" validate:"required,url"`
 	Alias string `json:"alias,omitempty"`
+	// ExpiresAt and TTL (a Go duration such as "72h") are mutually exclusive.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	TTL       string     `json:"ttl,omitempty"`
+	// MaxClicks burns the link after that many redirects; 0 means unlimited.
+	MaxClicks int64 `json:"max_clicks,omitempty" validate:"gte=0"`
+	// Tags must not contain commas, which separate them in CSV exports.
+	Tags []string `json:"tags,omitempty" validate:"max=10,dive,required,max=32,excludesall=0x2C"`
+	// RedirectType is the status redirects answer with; 0 means the
+	// configured default.
+	RedirectType int `json:"redirect_type,omitempty" validate:"omitempty,oneof=301 302 307 308"`
+	// QueryPassthrough merges the query of redirect requests into the
+	// destination; on conflicts the destination's value is kept, overridden
+	// or appended to.
+	QueryPassthrough string `json:"query_passthrough,omitempty" validate:"omitempty,oneof=keep override append"`
+	// PathPassthrough appends the path below the alias to the destination, so
+	// that /{alias}/guide leads to the guide page of the destination.
+	PathPassthrough bool `json:"path_passthrough,omitempty"`
 }
 
 type Response struct {
 	response.Response
-	Alias string `json:"alias,omitempty"`
+	Alias        string     `json:"alias,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	MaxClicks    int64      `json:"max_clicks,omitempty"`
+	RedirectType int        `json:"redirect_type,omitempty"`
 }
 
-// TODO: move to config
-const aliasLength = 6
-
 //go:generate go run github.com/vektra/mockery/v2@v2.43.2 --name=URLSaver
 type URLSaver interface {
-	SaveURL(urlToSave string, alias string) (int64, error)
+	NextURLID() (int64, error)
+	SaveURL(urlToSave string, alias string, userId int64, opts storage.URLOptions) (int64, error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.43.2 --name=AliasGenerator
+type AliasGenerator interface {
+	Generate(id int64) (string, error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.43.2 --name=AliasPolicy
+type AliasPolicy interface {
+	Normalize(alias string) string
+	Check(alias string) *validation.FieldError
+}
+
+var (
+	errExpiryConflict = validation.FieldError{
+		Field:   "ttl",
+		Rule:    "excluded_with",
+		Message: "expires_at and ttl are mutually exclusive",
+	}
+	errInvalidTTL = validation.FieldError{
+		Field:   "ttl",
+		Rule:    "duration",
+		Message: "ttl must be a positive duration",
+	}
+	errExpiryInPast = validation.FieldError{
+		Field:   "expires_at",
+		Rule:    "future",
+		Message: "expires_at must be in the future",
+	}
+)
+
+// ExpiresAt resolves the optional expires_at and ttl request fields to an
+// absolute time; the zero time means the link never expires.
+func ExpiresAt(expiresAt *time.Time, ttl string, now time.Time) (time.Time, *validation.FieldError) {
+	if expiresAt != nil && ttl != "" {
+		return time.Time{}, &errExpiryConflict
+	}
+
+	if ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil || d <= 0 {
+			return time.Time{}, &errInvalidTTL
+		}
+
+		return now.Add(d), nil
+	}
+
+	if expiresAt != nil {
+		if !expiresAt.After(now) {
+			return time.Time{}, &errExpiryInPast
+		}
+
+		return *expiresAt, nil
+	}
+
+	return time.Time{}, nil
+}
+
+// ErrNoFreeAlias means that every generated alias was taken or rejected.
+var ErrNoFreeAlias = errors.New("no free alias found")
+
+// InvalidError carries the field errors of a request that failed validation.
+type InvalidError struct {
+	Errors []validation.FieldError
 }
 
-func New(log *logger.Logger, urlSaver URLSaver) http.HandlerFunc {
+func (e *InvalidError) Error() string {
+	return fmt.Sprintf("invalid request: %v", e.Errors)
+}
+
+// Result describes a saved link.
+type Result struct {
+	ID        int64
+	Alias     string
+	ExpiresAt time.Time
+}
+
+// Save validates req and saves it for userId, generating an alias unless the
+// request has one. It fails with *InvalidError for requests the client has to
+// fix, storage.ErrURLExists when the requested alias is taken and
+// ErrNoFreeAlias when no generated alias was free.
+func Save(
+	urlSaver URLSaver,
+	aliases AliasGenerator,
+	policy AliasPolicy,
+	attempts int,
+	req Request,
+	userId int64,
+) (Result, error) {
+	if errs := validation.Struct(req); errs != nil {
+		return Result{}, &InvalidError{Errors: errs}
+	}
+
+	expiry, fieldErr := ExpiresAt(req.ExpiresAt, req.TTL, time.Now())
+	if fieldErr != nil {
+		return Result{}, &InvalidError{Errors: []validation.FieldError{*fieldErr}}
+	}
+
+	opts := storage.URLOptions{
+		ExpiresAt:    expiry,
+		MaxClicks:    req.MaxClicks,
+		Tags:         req.Tags,
+		RedirectType: req.RedirectType,
+		Passthrough: storage.Passthrough{
+			QueryPassthrough: req.QueryPassthrough,
+			PathPassthrough:  req.PathPassthrough,
+		},
+	}
+
+	res := Result{
+		Alias:     policy.Normalize(req.Alias),
+		ExpiresAt: expiry,
+	}
+
+	var err error
+	if res.Alias == "" {
+		res.Alias, res.ID, err = SaveGenerated(urlSaver, aliases, policy, attempts, req.URL, userId, opts)
+	} else {
+		if fieldErr := policy.Check(res.Alias); fieldErr != nil {
+			return Result{}, &InvalidError{Errors: []validation.FieldError{*fieldErr}}
+		}
+
+		res.ID, err = urlSaver.SaveURL(req.URL, res.Alias, userId, opts)
+	}
+	if err != nil {
+		return Result{}, err
+	}
+
+	return res, nil
+}
+
+// SaveGenerated saves the url under a generated alias. A taken alias, or one
+// the policy rejects (a reserved word, say), is regenerated from a fresh id
+// until attempts run out, which fails with ErrNoFreeAlias.
+func SaveGenerated(
+	urlSaver URLSaver,
+	aliases AliasGenerator,
+	policy AliasPolicy,
+	attempts int,
+	urlToSave string,
+	userId int64,
+	opts storage.URLOptions,
+) (string, int64, error) {
+	for attempt := 1; ; attempt++ {
+		id, err := urlSaver.NextURLID()
+		if err != nil {
+			return "", 0, err
+		}
+
+		alias, err := aliases.Generate(id)
+		if err != nil {
+			return "", 0, err
+		}
+
+		if policy.Check(alias) != nil {
+			if attempt < attempts {
+				continue
+			}
+			return "", 0, ErrNoFreeAlias
+		}
+
+		opts.ID = id
+		id, err = urlSaver.SaveURL(urlToSave, alias, userId, opts)
+		if errors.Is(err, storage.ErrURLExists) {
+			if attempt < attempts {
+				continue
+			}
+			return "", 0, ErrNoFreeAlias
+		}
+
+		return alias, id, err
+	}
+}
+
+// ErrorResponse maps an error of Save to the HTTP status and response the
+// save handler sends for it.
+func ErrorResponse(err error) (int, response.Response) {
+	var invalid *InvalidError
+
+	switch {
+	case errors.As(err, &invalid):
+		return http.StatusBadRequest, response.ValidationError(invalid.Errors...)
+	case errors.Is(err, storage.ErrURLExists):
+		return http.StatusConflict, response.Error(response.CodeAliasTaken, "alias already exists")
+	case errors.Is(err, ErrNoFreeAlias):
+		return http.StatusInternalServerError, response.Error(response.CodeInternal, "failed to generate alias")
+	default:
+		return http.StatusInternalServerError, response.Error(response.CodeInternal, "failed to add url")
+	}
+}
+
+func New(
+	log *logger.Logger,
+	urlSaver URLSaver,
+	aliases AliasGenerator,
+	policy AliasPolicy,
+	attempts int,
+) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const fn = "handlers.url.save.New"
 
@@ -40,46 +255,50 @@ func New(log *logger.Logger, urlSaver URLSaver) http.HandlerFunc {
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
+		userId, ok := auth.UserID(r.Context())
+		if !ok {
+			log.Error("user id is missing from context")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error(response.CodeUnauthorized, "unauthorized"))
+			return
+		}
+
 		var req Request
 
 		err := render.DecodeJSON(r.Body, &req)
 		if err != nil {
 			log.Error("failed to decode request body", slog.String("error", err.Error()))
-			render.JSON(w, r, response.Error("failed to decode request body"))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(response.CodeInvalidRequest, "failed to decode request body"))
 			return
 		}
 
-		if err := validator.New().Struct(req); err != nil {
-			log.Error("invalid request", slog.String("error", err.Error()))
-			// TODO: move to Validator
-			render.JSON(w, r, response.Error("failed to validate request URL"))
-			return
-		}
+		res, err := Save(urlSaver, aliases, policy, attempts, req, userId)
+		if err != nil {
+			status, resp := ErrorResponse(err)
+			if status >= http.StatusInternalServerError {
+				log.Error("failed to add url", slog.String("error", err.Error()))
+			} else {
+				log.Info("url not added", slog.String("error", err.Error()))
+			}
 
-		alias := req.Alias
-		if alias == "" {
-			alias = random.String(aliasLength)
+			render.Status(r, status)
+			render.JSON(w, r, resp)
+			return
 		}
 
-		// TODO: prevent alias collision
+		log.Info("url added", slog.Int64("id", res.ID))
 
-		id, err := urlSaver.SaveURL(req.URL, alias)
-		if errors.Is(err, storage.ErrURLExists) {
-			log.Info("url already exists", slog.String("url", req.URL))
-			render.JSON(w, r, response.Error("url already exists"))
-			return
+		resp := Response{
+			Response:     response.OK(),
+			Alias:        res.Alias,
+			MaxClicks:    req.MaxClicks,
+			RedirectType: req.RedirectType,
 		}
-		if err != nil {
-			log.Error("failed to add url", slog.String("error", err.Error()))
-			render.JSON(w, r, response.Error("failed to add url"))
-			return
+		if !res.ExpiresAt.IsZero() {
+			resp.ExpiresAt = &res.ExpiresAt
 		}
 
-		log.Info("url added", slog.Int64("id", id))
-
-		render.JSON(w, r, Response{
-			Response: response.OK(),
-			Alias:    alias,
-		})
+		render.JSON(w, r, resp)
 	}
 }
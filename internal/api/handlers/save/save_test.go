@@ -9,47 +9,206 @@ import (
 	"github.com/stretchr/testify/require"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"testing"
 	"url-shortener/internal/api/handlers/save/mocks"
+	"url-shortener/internal/api/middleware/auth"
+	"url-shortener/internal/api/response"
 	"url-shortener/internal/lib/logger/handlers"
+	"url-shortener/internal/lib/validation"
+	"url-shortener/internal/storage"
 )
 
+const testUserId int64 = 1
+
 func TestSaveHandler(t *testing.T) {
 	tests := []struct {
-		name      string
-		alias     string
-		url       string
-		respError string
-		mockError error
+		name       string
+		alias      string
+		url        string
+		ttl        string
+		expiresAt  string
+		maxClicks  int64
+		tags       []string
+		redirect   int
+		query      string
+		respError  string
+		respCode   string
+		respStatus int
+		respFields []string
+		mockError  error
+		policyErr  *validation.FieldError
 	}{
 		{
-			name:  "Success",
-			alias: "test_alias",
-			url:   "https://google.com",
+			name:       "Success",
+			alias:      "test_alias",
+			url:        "https://google.com",
+			respStatus: http.StatusOK,
+		},
+		{
+			name:       "Empty alias",
+			alias:      "",
+			url:        "https://google.com",
+			respStatus: http.StatusOK,
+		},
+		{
+			name:       "Empty URL",
+			url:        "",
+			alias:      "some_alias",
+			respError:  "validation failed",
+			respCode:   response.CodeValidationFailed,
+			respStatus: http.StatusBadRequest,
+			respFields: []string{"url:required"},
+		},
+		{
+			name:       "Invalid URL",
+			url:        "some invalid URL",
+			alias:      "some_alias",
+			respError:  "validation failed",
+			respCode:   response.CodeValidationFailed,
+			respStatus: http.StatusBadRequest,
+			respFields: []string{"url:url"},
+		},
+		{
+			name:       "With TTL",
+			alias:      "ttl_alias",
+			url:        "https://google.com",
+			ttl:        "72h",
+			respStatus: http.StatusOK,
+		},
+		{
+			name:       "With expiry",
+			alias:      "expiring_alias",
+			url:        "https://google.com",
+			expiresAt:  "2999-01-01T00:00:00Z",
+			respStatus: http.StatusOK,
 		},
 		{
-			name:  "Empty alias",
-			alias: "",
-			url:   "https://google.com",
+			name:       "Invalid TTL",
+			alias:      "some_alias",
+			url:        "https://google.com",
+			ttl:        "-1h",
+			respError:  "validation failed",
+			respCode:   response.CodeValidationFailed,
+			respStatus: http.StatusBadRequest,
+			respFields: []string{"ttl:duration"},
 		},
 		{
-			name:      "Empty URL",
-			url:       "",
-			alias:     "some_alias",
-			respError: "failed to validate request URL",
+			name:       "Expiry in the past",
+			alias:      "some_alias",
+			url:        "https://google.com",
+			expiresAt:  "2000-01-01T00:00:00Z",
+			respError:  "validation failed",
+			respCode:   response.CodeValidationFailed,
+			respStatus: http.StatusBadRequest,
+			respFields: []string{"expires_at:future"},
 		},
 		{
-			name:      "Invalid URL",
-			url:       "some invalid URL",
-			alias:     "some_alias",
-			respError: "failed to validate request URL",
+			name:       "TTL and expiry",
+			alias:      "some_alias",
+			url:        "https://google.com",
+			ttl:        "1h",
+			expiresAt:  "2999-01-01T00:00:00Z",
+			respError:  "validation failed",
+			respCode:   response.CodeValidationFailed,
+			respStatus: http.StatusBadRequest,
+			respFields: []string{"ttl:excluded_with"},
 		},
 		{
-			name:      "SaveURL Error",
-			alias:     "test_alias",
-			url:       "https://google.com",
-			respError: "failed to add url",
-			mockError: errors.New("unexpected error"),
+			name:       "With click limit",
+			alias:      "limited_alias",
+			url:        "https://google.com",
+			maxClicks:  3,
+			respStatus: http.StatusOK,
+		},
+		{
+			name:       "Negative click limit",
+			alias:      "some_alias",
+			url:        "https://google.com",
+			maxClicks:  -1,
+			respError:  "validation failed",
+			respCode:   response.CodeValidationFailed,
+			respStatus: http.StatusBadRequest,
+			respFields: []string{"max_clicks:gte"},
+		},
+		{
+			name:       "With tags",
+			alias:      "tagged_alias",
+			url:        "https://google.com",
+			tags:       []string{"docs", "work"},
+			respStatus: http.StatusOK,
+		},
+		{
+			name:       "Empty tag",
+			alias:      "some_alias",
+			url:        "https://google.com",
+			tags:       []string{""},
+			respError:  "validation failed",
+			respCode:   response.CodeValidationFailed,
+			respStatus: http.StatusBadRequest,
+			respFields: []string{"tags[0]:required"},
+		},
+		{
+			name:       "Permanent redirect",
+			alias:      "moved_alias",
+			url:        "https://google.com",
+			redirect:   http.StatusMovedPermanently,
+			respStatus: http.StatusOK,
+		},
+		{
+			name:       "Invalid redirect type",
+			alias:      "some_alias",
+			url:        "https://google.com",
+			redirect:   http.StatusSeeOther,
+			respError:  "validation failed",
+			respCode:   response.CodeValidationFailed,
+			respStatus: http.StatusBadRequest,
+			respFields: []string{"redirect_type:oneof"},
+		},
+		{
+			name:       "Query passthrough",
+			alias:      "docs_alias",
+			url:        "https://google.com",
+			query:      "override",
+			respStatus: http.StatusOK,
+		},
+		{
+			name:       "Invalid query passthrough",
+			alias:      "some_alias",
+			url:        "https://google.com",
+			query:      "merge",
+			respError:  "validation failed",
+			respCode:   response.CodeValidationFailed,
+			respStatus: http.StatusBadRequest,
+			respFields: []string{"query_passthrough:oneof"},
+		},
+		{
+			name:       "Alias rejected by policy",
+			alias:      "login",
+			url:        "https://google.com",
+			respError:  "validation failed",
+			respCode:   response.CodeValidationFailed,
+			respStatus: http.StatusBadRequest,
+			respFields: []string{"alias:reserved"},
+			policyErr:  &validation.FieldError{Field: "alias", Rule: "reserved", Message: "alias \"login\" is reserved"},
+		},
+		{
+			name:       "Alias taken",
+			alias:      "taken_alias",
+			url:        "https://google.com",
+			respError:  "alias already exists",
+			respCode:   response.CodeAliasTaken,
+			respStatus: http.StatusConflict,
+			mockError:  storage.ErrURLExists,
+		},
+		{
+			name:       "SaveURL Error",
+			alias:      "test_alias",
+			url:        "https://google.com",
+			respError:  "failed to add url",
+			respCode:   response.CodeInternal,
+			respStatus: http.StatusInternalServerError,
+			mockError:  errors.New("unexpected error"),
 		},
 	}
 
@@ -59,24 +218,65 @@ func TestSaveHandler(t *testing.T) {
 			t.Parallel()
 
 			urlSaverMock := mocks.NewURLSaver(t)
+			aliasGeneratorMock := mocks.NewAliasGenerator(t)
+			aliasPolicyMock := mocks.NewAliasPolicy(t)
+
+			aliasPolicyMock.On("Normalize", tc.alias).Return(tc.alias).Maybe()
+			aliasPolicyMock.On("Check", mock.AnythingOfType("string")).Return(tc.policyErr).Maybe()
 
 			if tc.respError == "" || tc.mockError != nil {
-				urlSaverMock.On("SaveURL", tc.url, mock.AnythingOfType("string")).
-					Return(int64(1), tc.mockError).
+				alias := tc.alias
+				if alias == "" {
+					alias = "generated"
+
+					urlSaverMock.On("NextURLID").
+						Return(int64(7), nil).
+						Once()
+					aliasGeneratorMock.On("Generate", int64(7)).
+						Return(alias, nil).
+						Once()
+				}
+
+				expiring := tc.ttl != "" || tc.expiresAt != ""
+				opts := mock.MatchedBy(func(opts storage.URLOptions) bool {
+					return opts.ExpiresAt.IsZero() != expiring && opts.MaxClicks == tc.maxClicks &&
+						slices.Equal(opts.Tags, tc.tags) && opts.RedirectType == tc.redirect &&
+						opts.QueryPassthrough == tc.query
+				})
+
+				urlSaverMock.On("SaveURL", tc.url, alias, testUserId, opts).
+					Return(int64(7), tc.mockError).
 					Once()
 			}
 
-			handler := New(handlers.NewDiscardLogger(), urlSaverMock)
+			handler := New(handlers.NewDiscardLogger(), urlSaverMock, aliasGeneratorMock, aliasPolicyMock, 1)
 
-			input := fmt.Sprintf(`{"url": "%s", "alias": "%s"}`, tc.url, tc.alias)
+			input := fmt.Sprintf(`{"url": "%s", "alias": "%s", "ttl": "%s", "max_clicks": %d`, tc.url, tc.alias, tc.ttl, tc.maxClicks)
+			if tc.expiresAt != "" {
+				input += fmt.Sprintf(`, "expires_at": "%s"`, tc.expiresAt)
+			}
+			if tc.tags != nil {
+				tags, err := json.Marshal(tc.tags)
+				require.NoError(t, err)
+				input += fmt.Sprintf(`, "tags": %s`, tags)
+			}
+			if tc.query != "" {
+				input += fmt.Sprintf(`, "query_passthrough": "%s"`, tc.query)
+			}
+			if tc.redirect != 0 {
+				input += fmt.Sprintf(`, "redirect_type": %d`, tc.redirect)
+			}
+			input += "}"
 
 			req, err := http.NewRequest(http.MethodPost, "/url", bytes.NewReader([]byte(input)))
 			require.NoError(t, err)
 
+			req = req.WithContext(auth.WithUserID(req.Context(), testUserId))
+
 			rr := httptest.NewRecorder()
 			handler.ServeHTTP(rr, req)
 
-			require.Equal(t, rr.Code, http.StatusOK)
+			require.Equal(t, tc.respStatus, rr.Code)
 
 			body := rr.Body.String()
 
@@ -86,11 +286,118 @@ func TestSaveHandler(t *testing.T) {
 
 			if tc.respError != "" {
 				require.Equal(t, tc.respError, resp.Error)
+				require.Equal(t, tc.respCode, resp.Code)
+				require.Equal(t, tc.respFields, fieldRules(resp.Errors))
 			} else {
 				require.Empty(t, resp.Error)
+				require.Equal(t, tc.ttl != "" || tc.expiresAt != "", resp.ExpiresAt != nil)
+				require.Equal(t, tc.maxClicks, resp.MaxClicks)
+				require.Equal(t, tc.redirect, resp.RedirectType)
 			}
 
 			require.Equal(t, tc.respError, resp.Error)
 		})
 	}
 }
+
+func TestSaveHandler_GeneratedAlias(t *testing.T) {
+	rejected := &validation.FieldError{Field: "alias", Rule: "reserved", Message: "alias is reserved"}
+
+	tests := []struct {
+		name       string
+		attempts   int
+		rejected   int
+		taken      int
+		respAlias  string
+		respStatus int
+	}{
+		{
+			name:       "Free",
+			attempts:   3,
+			respAlias:  "alias1",
+			respStatus: http.StatusOK,
+		},
+		{
+			name:       "Taken",
+			attempts:   3,
+			taken:      2,
+			respAlias:  "alias3",
+			respStatus: http.StatusOK,
+		},
+		{
+			name:       "Rejected by policy",
+			attempts:   3,
+			rejected:   1,
+			taken:      1,
+			respAlias:  "alias3",
+			respStatus: http.StatusOK,
+		},
+		{
+			name:       "Attempts exhausted",
+			attempts:   2,
+			taken:      2,
+			respStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlSaverMock := mocks.NewURLSaver(t)
+			aliasGeneratorMock := mocks.NewAliasGenerator(t)
+			aliasPolicyMock := mocks.NewAliasPolicy(t)
+
+			aliasPolicyMock.On("Normalize", "").Return("").Once()
+
+			for id := int64(1); id <= int64(min(tc.rejected+tc.taken+1, tc.attempts)); id++ {
+				alias := fmt.Sprintf("alias%d", id)
+
+				urlSaverMock.On("NextURLID").Return(id, nil).Once()
+				aliasGeneratorMock.On("Generate", id).Return(alias, nil).Once()
+
+				if id <= int64(tc.rejected) {
+					aliasPolicyMock.On("Check", alias).Return(rejected).Once()
+					continue
+				}
+				aliasPolicyMock.On("Check", alias).Return(nil).Once()
+
+				var err error
+				if id <= int64(tc.rejected+tc.taken) {
+					err = storage.ErrURLExists
+				}
+
+				urlSaverMock.On("SaveURL", "https://google.com", alias, testUserId, storage.URLOptions{ID: id}).
+					Return(id, err).
+					Once()
+			}
+
+			handler := New(handlers.NewDiscardLogger(), urlSaverMock, aliasGeneratorMock, aliasPolicyMock, tc.attempts)
+
+			req, err := http.NewRequest(http.MethodPost, "/url", bytes.NewReader([]byte(`{"url": "https://google.com"}`)))
+			require.NoError(t, err)
+
+			req = req.WithContext(auth.WithUserID(req.Context(), testUserId))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respStatus, rr.Code)
+
+			var resp Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respAlias, resp.Alias)
+		})
+	}
+}
+
+// fieldRules flattens field errors to "field:rule" pairs for comparison.
+func fieldRules(errs []validation.FieldError) []string {
+	var rules []string
+	for _, e := range errs {
+		rules = append(rules, e.Field+":"+e.Rule)
+	}
+	return rules
+}
@@ -0,0 +1,97 @@
+package stats
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"time"
+	"url-shortener/internal/api/middleware/auth"
+	"url-shortener/internal/api/response"
+	"url-shortener/internal/lib/logger"
+	"url-shortener/internal/storage"
+)
+
+type Response struct {
+	response.Response
+	Alias          string `json:"alias"`
+	TotalClicks    int64  `json:"total_clicks"`
+	UniqueVisitors int64  `json:"unique_visitors"`
+	Daily          []Day  `json:"daily"`
+}
+
+type Day struct {
+	// Date is a UTC calendar day such as "2024-03-10".
+	Date   string `json:"date"`
+	Clicks int64  `json:"clicks"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.43.2 --name=StatsGetter
+type StatsGetter interface {
+	GetURLStats(alias string, userId int64) (storage.URLStats, error)
+}
+
+func New(log *logger.Logger, statsGetter StatsGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.url.stats.New"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userId, ok := auth.UserID(r.Context())
+		if !ok {
+			log.Error("user id is missing from context")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error(response.CodeUnauthorized, "unauthorized"))
+			return
+		}
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias is empty")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(response.CodeInvalidRequest, "invalid request"))
+			return
+		}
+
+		stats, err := statsGetter.GetURLStats(alias, userId)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error(response.CodeNotFound, "not found"))
+			return
+		}
+		if errors.Is(err, storage.ErrForbidden) {
+			log.Info("url is owned by another user", slog.String("alias", alias))
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, response.Error(response.CodeForbidden, "forbidden"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url stats", slog.String("error", err.Error()))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error(response.CodeInternal, "internal error"))
+			return
+		}
+
+		resp := Response{
+			Response:       response.OK(),
+			Alias:          alias,
+			TotalClicks:    stats.TotalClicks,
+			UniqueVisitors: stats.UniqueVisitors,
+			Daily:          make([]Day, 0, len(stats.Daily)),
+		}
+		for _, d := range stats.Daily {
+			resp.Daily = append(resp.Daily, Day{
+				Date:   d.Date.UTC().Format(time.DateOnly),
+				Clicks: d.Clicks,
+			})
+		}
+
+		render.JSON(w, r, resp)
+	}
+}
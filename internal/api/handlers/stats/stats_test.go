@@ -0,0 +1,119 @@
+package stats
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"url-shortener/internal/api/handlers/stats/mocks"
+	"url-shortener/internal/api/middleware/auth"
+	"url-shortener/internal/api/response"
+	"url-shortener/internal/lib/logger/handlers"
+	"url-shortener/internal/storage"
+)
+
+const testUserId int64 = 1
+
+func TestStatsHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		alias      string
+		stats      storage.URLStats
+		respDaily  []Day
+		respError  string
+		respCode   string
+		respStatus int
+		mockError  error
+	}{
+		{
+			name:  "Success",
+			alias: "test_alias",
+			stats: storage.URLStats{
+				TotalClicks:    3,
+				UniqueVisitors: 2,
+				Daily: []storage.DailyClicks{
+					{Date: time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC), Clicks: 1},
+					{Date: time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC), Clicks: 2},
+				},
+			},
+			respDaily: []Day{
+				{Date: "2024-03-09", Clicks: 1},
+				{Date: "2024-03-10", Clicks: 2},
+			},
+			respStatus: http.StatusOK,
+		},
+		{
+			name:       "No clicks",
+			alias:      "test_alias",
+			respDaily:  []Day{},
+			respStatus: http.StatusOK,
+		},
+		{
+			name:       "Alias not found",
+			alias:      "non_existing_alias",
+			respError:  "not found",
+			respCode:   response.CodeNotFound,
+			respStatus: http.StatusNotFound,
+			mockError:  storage.ErrURLNotFound,
+		},
+		{
+			name:       "Owned by another user",
+			alias:      "foreign_alias",
+			respError:  "forbidden",
+			respCode:   response.CodeForbidden,
+			respStatus: http.StatusForbidden,
+			mockError:  storage.ErrForbidden,
+		},
+		{
+			name:       "Storage error",
+			alias:      "test_alias",
+			respError:  "internal error",
+			respCode:   response.CodeInternal,
+			respStatus: http.StatusInternalServerError,
+			mockError:  errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			statsGetterMock := mocks.NewStatsGetter(t)
+			statsGetterMock.On("GetURLStats", tc.alias, testUserId).
+				Return(tc.stats, tc.mockError).
+				Once()
+
+			handler := New(handlers.NewDiscardLogger(), statsGetterMock)
+
+			router := chi.NewRouter()
+			router.Get("/{alias}/stats", handler)
+
+			req, err := http.NewRequest(http.MethodGet, "/"+tc.alias+"/stats", nil)
+			require.NoError(t, err)
+
+			req = req.WithContext(auth.WithUserID(req.Context(), testUserId))
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respStatus, rr.Code)
+
+			var resp Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.respCode, resp.Code)
+			if tc.respError == "" {
+				require.Equal(t, tc.alias, resp.Alias)
+				require.Equal(t, tc.stats.TotalClicks, resp.TotalClicks)
+				require.Equal(t, tc.stats.UniqueVisitors, resp.UniqueVisitors)
+				require.Equal(t, tc.respDaily, resp.Daily)
+			}
+		})
+	}
+}
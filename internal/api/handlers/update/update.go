@@ -0,0 +1,167 @@
+package update
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"time"
+	"url-shortener/internal/api/handlers/save"
+	"url-shortener/internal/api/middleware/auth"
+	"url-shortener/internal/api/response"
+	"url-shortener/internal/lib/logger"
+	"url-shortener/internal/lib/validation"
+	"url-shortener/internal/storage"
+)
+
+// Request changes the fields that are set and leaves the others alone.
+type Request struct {
+	URL string `json:"url,omitempty" validate:"omitempty,url"`
+	// ExpiresAt and TTL set a new expiry as in save.Request; NoExpiry removes
+	// it.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	TTL       string     `json:"ttl,omitempty"`
+	NoExpiry  bool       `json:"no_expiry,omitempty"`
+	// RedirectType sets the status redirects answer with; 0 restores the
+	// configured default.
+	RedirectType *int `json:"redirect_type,omitempty" validate:"omitempty,oneof=0 301 302 307 308"`
+	// QueryPassthrough takes the save.Request policies, or "off" to stop
+	// passing the query through.
+	QueryPassthrough *string `json:"query_passthrough,omitempty" validate:"omitempty,oneof=off keep override append"`
+	PathPassthrough  *bool   `json:"path_passthrough,omitempty"`
+}
+
+type Response struct {
+	response.Response
+	Alias     string     `json:"alias,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+var errNoExpiryConflict = validation.FieldError{
+	Field:   "no_expiry",
+	Rule:    "excluded_with",
+	Message: "no_expiry cannot be combined with expires_at or ttl",
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.43.2 --name=URLUpdater
+type URLUpdater interface {
+	UpdateURL(alias string, userId int64, upd storage.URLUpdate) error
+}
+
+func New(log *logger.Logger, urlUpdater URLUpdater) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.url.update.New"
+
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userId, ok := auth.UserID(r.Context())
+		if !ok {
+			log.Error("user id is missing from context")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error(response.CodeUnauthorized, "unauthorized"))
+			return
+		}
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias is empty")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(response.CodeInvalidRequest, "invalid request"))
+			return
+		}
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request body", slog.String("error", err.Error()))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(response.CodeInvalidRequest, "failed to decode request body"))
+			return
+		}
+
+		if errs := validation.Struct(req); errs != nil {
+			log.Info("invalid request", slog.Any("errors", errs))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.ValidationError(errs...))
+			return
+		}
+
+		if req.NoExpiry && (req.ExpiresAt != nil || req.TTL != "") {
+			log.Info("invalid expiry", slog.String("error", errNoExpiryConflict.Error()))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.ValidationError(errNoExpiryConflict))
+			return
+		}
+
+		upd := storage.URLUpdate{
+			RedirectType:    req.RedirectType,
+			PathPassthrough: req.PathPassthrough,
+		}
+		if req.URL != "" {
+			upd.URL = &req.URL
+		}
+		if req.QueryPassthrough != nil {
+			query := *req.QueryPassthrough
+			if query == "off" {
+				query = ""
+			}
+			upd.QueryPassthrough = &query
+		}
+
+		expiry, fieldErr := save.ExpiresAt(req.ExpiresAt, req.TTL, time.Now())
+		if fieldErr != nil {
+			log.Info("invalid expiry", slog.String("error", fieldErr.Error()))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.ValidationError(*fieldErr))
+			return
+		}
+		if !expiry.IsZero() || req.NoExpiry {
+			upd.ExpiresAt = &expiry
+		}
+
+		if upd == (storage.URLUpdate{}) {
+			log.Info("nothing to update")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(response.CodeValidationFailed, "nothing to update"))
+			return
+		}
+
+		err = urlUpdater.UpdateURL(alias, userId, upd)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error(response.CodeNotFound, "not found"))
+			return
+		}
+		if errors.Is(err, storage.ErrForbidden) {
+			log.Info("url is owned by another user", slog.String("alias", alias))
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, response.Error(response.CodeForbidden, "forbidden"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to update url", slog.String("error", err.Error()))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error(response.CodeInternal, "failed to update url"))
+			return
+		}
+
+		log.Info("url updated", slog.String("alias", alias))
+
+		resp := Response{
+			Response: response.OK(),
+			Alias:    alias,
+		}
+		if !expiry.IsZero() {
+			resp.ExpiresAt = &expiry
+		}
+
+		render.JSON(w, r, resp)
+	}
+}
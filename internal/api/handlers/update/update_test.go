@@ -0,0 +1,215 @@
+package update
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"url-shortener/internal/api/handlers/update/mocks"
+	"url-shortener/internal/api/middleware/auth"
+	"url-shortener/internal/api/response"
+	"url-shortener/internal/lib/logger/handlers"
+	"url-shortener/internal/storage"
+)
+
+const testUserId int64 = 1
+
+func TestUpdateHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantURL    string
+		wantExpiry string // "", "set" or "cleared"
+		wantType   *int
+		wantQuery  *string
+		wantPath   *bool
+		respError  string
+		respCode   string
+		respStatus int
+		mockError  error
+	}{
+		{
+			name:       "New destination",
+			body:       `{"url": "https://example.com/new"}`,
+			wantURL:    "https://example.com/new",
+			respStatus: http.StatusOK,
+		},
+		{
+			name:       "New expiry",
+			body:       `{"ttl": "24h"}`,
+			wantExpiry: "set",
+			respStatus: http.StatusOK,
+		},
+		{
+			name:       "Expiry removed",
+			body:       `{"url": "https://example.com/new", "no_expiry": true}`,
+			wantURL:    "https://example.com/new",
+			wantExpiry: "cleared",
+			respStatus: http.StatusOK,
+		},
+		{
+			name:       "New redirect type",
+			body:       `{"redirect_type": 308}`,
+			wantType:   ptr(http.StatusPermanentRedirect),
+			respStatus: http.StatusOK,
+		},
+		{
+			name:       "Default redirect type restored",
+			body:       `{"redirect_type": 0}`,
+			wantType:   ptr(0),
+			respStatus: http.StatusOK,
+		},
+		{
+			name:       "Invalid redirect type",
+			body:       `{"redirect_type": 303}`,
+			respError:  "validation failed",
+			respCode:   response.CodeValidationFailed,
+			respStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Passthrough enabled",
+			body:       `{"query_passthrough": "append", "path_passthrough": true}`,
+			wantQuery:  ptr("append"),
+			wantPath:   ptr(true),
+			respStatus: http.StatusOK,
+		},
+		{
+			name:       "Query passthrough turned off",
+			body:       `{"query_passthrough": "off"}`,
+			wantQuery:  ptr(""),
+			respStatus: http.StatusOK,
+		},
+		{
+			name:       "Invalid query passthrough",
+			body:       `{"query_passthrough": ""}`,
+			respError:  "validation failed",
+			respCode:   response.CodeValidationFailed,
+			respStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Nothing to update",
+			body:       `{}`,
+			respError:  "nothing to update",
+			respCode:   response.CodeValidationFailed,
+			respStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Invalid URL",
+			body:       `{"url": "not a url"}`,
+			respError:  "validation failed",
+			respCode:   response.CodeValidationFailed,
+			respStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Expiry conflict",
+			body:       `{"ttl": "1h", "no_expiry": true}`,
+			respError:  "validation failed",
+			respCode:   response.CodeValidationFailed,
+			respStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Expiry in the past",
+			body:       `{"expires_at": "2000-01-01T00:00:00Z"}`,
+			respError:  "validation failed",
+			respCode:   response.CodeValidationFailed,
+			respStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Alias not found",
+			body:       `{"url": "https://example.com/new"}`,
+			wantURL:    "https://example.com/new",
+			respError:  "not found",
+			respCode:   response.CodeNotFound,
+			respStatus: http.StatusNotFound,
+			mockError:  storage.ErrURLNotFound,
+		},
+		{
+			name:       "Owned by another user",
+			body:       `{"url": "https://example.com/new"}`,
+			wantURL:    "https://example.com/new",
+			respError:  "forbidden",
+			respCode:   response.CodeForbidden,
+			respStatus: http.StatusForbidden,
+			mockError:  storage.ErrForbidden,
+		},
+		{
+			name:       "Storage error",
+			body:       `{"url": "https://example.com/new"}`,
+			wantURL:    "https://example.com/new",
+			respError:  "failed to update url",
+			respCode:   response.CodeInternal,
+			respStatus: http.StatusInternalServerError,
+			mockError:  errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlUpdaterMock := mocks.NewURLUpdater(t)
+
+			if tc.respError == "" || tc.mockError != nil {
+				upd := mock.MatchedBy(func(upd storage.URLUpdate) bool {
+					if (upd.URL != nil) != (tc.wantURL != "") || upd.URL != nil && *upd.URL != tc.wantURL {
+						return false
+					}
+					if !equalPtr(upd.RedirectType, tc.wantType) || !equalPtr(upd.QueryPassthrough, tc.wantQuery) ||
+						!equalPtr(upd.PathPassthrough, tc.wantPath) {
+						return false
+					}
+
+					switch tc.wantExpiry {
+					case "set":
+						return upd.ExpiresAt != nil && !upd.ExpiresAt.IsZero()
+					case "cleared":
+						return upd.ExpiresAt != nil && upd.ExpiresAt.IsZero()
+					default:
+						return upd.ExpiresAt == nil
+					}
+				})
+
+				urlUpdaterMock.On("UpdateURL", "test_alias", testUserId, upd).
+					Return(tc.mockError).
+					Once()
+			}
+
+			router := chi.NewRouter()
+			router.Patch("/{alias}", New(handlers.NewDiscardLogger(), urlUpdaterMock))
+
+			req, err := http.NewRequest(http.MethodPatch, "/test_alias", bytes.NewReader([]byte(tc.body)))
+			require.NoError(t, err)
+
+			req = req.WithContext(auth.WithUserID(req.Context(), testUserId))
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respStatus, rr.Code)
+
+			var resp Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.respCode, resp.Code)
+			if tc.respError == "" {
+				require.Equal(t, "test_alias", resp.Alias)
+				require.Equal(t, tc.wantExpiry == "set", resp.ExpiresAt != nil)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func equalPtr[T comparable](a, b *T) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
+}
@@ -0,0 +1,31 @@
+package alias
+
+import (
+	"github.com/go-chi/chi/v5"
+	"net/http"
+)
+
+type Normalizer interface {
+	Normalize(alias string) string
+}
+
+// New rewrites the {alias} URL parameter to the form aliases are stored under.
+// URL parameters are only set once the route is matched, so the middleware
+// must wrap the route handlers, e.g. through chi's With or Group.
+func New(normalizer Normalizer) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				for i, key := range rctx.URLParams.Keys {
+					if key == "alias" {
+						rctx.URLParams.Values[i] = normalizer.Normalize(rctx.URLParams.Values[i])
+					}
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
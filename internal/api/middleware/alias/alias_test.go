@@ -0,0 +1,35 @@
+package alias
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type lowerCase struct{}
+
+func (lowerCase) Normalize(alias string) string {
+	return strings.ToLower(alias)
+}
+
+func TestAliasMiddleware(t *testing.T) {
+	router := chi.NewRouter()
+	router.Group(func(r chi.Router) {
+		r.Use(New(lowerCase{}))
+
+		r.Get("/{alias}/stats", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(chi.URLParam(r, "alias")))
+		})
+	})
+
+	req, err := http.NewRequest(http.MethodGet, "/MyLink/stats", nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	require.Equal(t, "mylink", rr.Body.String())
+}
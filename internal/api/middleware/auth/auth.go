@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	"url-shortener/internal/api/response"
+	"url-shortener/internal/lib/logger"
+	"url-shortener/internal/storage"
+)
+
+type ctxKey int
+
+const (
+	userIDKey ctxKey = iota
+	tokenKey
+)
+
+const bearerPrefix = "Bearer "
+
+//go:generate go run github.com/vektra/mockery/v2@v2.43.2 --name=SessionProvider
+type SessionProvider interface {
+	GetSessionUserID(token string) (int64, error)
+	ExtendSession(token string, expiresAt time.Time) error
+}
+
+// New authenticates requests by their bearer session token. With sliding
+// enabled every authenticated request pushes the session expiry to now+ttl.
+func New(log *logger.Logger, sessionProvider SessionProvider, ttl time.Duration, sliding bool) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("context", "middleware/auth"),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			log := log.With(
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+			)
+
+			token := bearerToken(r)
+			if token == "" {
+				log.Info("missing bearer token")
+				unauthorized(w, r)
+				return
+			}
+
+			userId, err := sessionProvider.GetSessionUserID(token)
+			if errors.Is(err, storage.ErrSessionNotFound) {
+				log.Info("session not found")
+				unauthorized(w, r)
+				return
+			}
+			if err != nil {
+				log.Error("failed to get session", slog.String("error", err.Error()))
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, response.Error(response.CodeInternal, "internal error"))
+				return
+			}
+
+			if sliding {
+				err = sessionProvider.ExtendSession(token, time.Now().Add(ttl))
+				if err != nil {
+					log.Error("failed to extend session", slog.String("error", err.Error()))
+				}
+			}
+
+			ctx := WithUserID(r.Context(), userId)
+			ctx = WithToken(ctx, token)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// WithUserID returns a copy of ctx carrying the authenticated user ID.
+func WithUserID(ctx context.Context, userId int64) context.Context {
+	return context.WithValue(ctx, userIDKey, userId)
+}
+
+// UserID returns the authenticated user ID stored in ctx by the middleware.
+func UserID(ctx context.Context) (int64, bool) {
+	userId, ok := ctx.Value(userIDKey).(int64)
+	return userId, ok
+}
+
+// WithToken returns a copy of ctx carrying the session token.
+func WithToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenKey, token)
+}
+
+// Token returns the session token the request was authenticated with.
+func Token(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(tokenKey).(string)
+	return token, ok
+}
+
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) <= len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return ""
+	}
+
+	return strings.TrimSpace(header[len(bearerPrefix):])
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	render.Status(r, http.StatusUnauthorized)
+	render.JSON(w, r, response.Error(response.CodeUnauthorized, "unauthorized"))
+}
@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+	"url-shortener/internal/api/middleware/auth/mocks"
+	"url-shortener/internal/api/response"
+	"url-shortener/internal/lib/logger/handlers"
+	"url-shortener/internal/storage"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		token      string
+		userId     int64
+		sliding    bool
+		mockError  error
+		respStatus int
+		respError  string
+	}{
+		{
+			name:       "Valid token",
+			header:     "Bearer valid_token",
+			token:      "valid_token",
+			userId:     42,
+			respStatus: http.StatusOK,
+		},
+		{
+			name:       "Lowercase scheme",
+			header:     "bearer valid_token",
+			token:      "valid_token",
+			userId:     42,
+			respStatus: http.StatusOK,
+		},
+		{
+			name:       "Sliding expiry",
+			header:     "Bearer valid_token",
+			token:      "valid_token",
+			userId:     42,
+			sliding:    true,
+			respStatus: http.StatusOK,
+		},
+		{
+			name:       "Missing header",
+			header:     "",
+			respStatus: http.StatusUnauthorized,
+			respError:  "unauthorized",
+		},
+		{
+			name:       "Wrong scheme",
+			header:     "Basic dXNlcjpwYXNz",
+			respStatus: http.StatusUnauthorized,
+			respError:  "unauthorized",
+		},
+		{
+			name:       "Unknown token",
+			header:     "Bearer unknown_token",
+			token:      "unknown_token",
+			mockError:  storage.ErrSessionNotFound,
+			respStatus: http.StatusUnauthorized,
+			respError:  "unauthorized",
+		},
+		{
+			name:       "Storage error",
+			header:     "Bearer some_token",
+			token:      "some_token",
+			mockError:  errors.New("internal error"),
+			respStatus: http.StatusInternalServerError,
+			respError:  "internal error",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			sessionProviderMock := mocks.NewSessionProvider(t)
+
+			if tc.token != "" {
+				sessionProviderMock.On("GetSessionUserID", tc.token).
+					Return(tc.userId, tc.mockError).
+					Once()
+			}
+
+			if tc.sliding {
+				sessionProviderMock.On("ExtendSession", tc.token, mock.AnythingOfType("time.Time")).
+					Return(nil).
+					Once()
+			}
+
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				userId, ok := UserID(r.Context())
+				require.True(t, ok)
+
+				token, ok := Token(r.Context())
+				require.True(t, ok)
+				require.Equal(t, tc.token, token)
+
+				_, _ = w.Write([]byte(strconv.FormatInt(userId, 10)))
+			})
+
+			handler := New(handlers.NewDiscardLogger(), sessionProviderMock, time.Hour, tc.sliding)(next)
+
+			req, err := http.NewRequest(http.MethodPost, "/save", nil)
+			require.NoError(t, err)
+
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respStatus, rr.Code)
+
+			if tc.respError == "" {
+				require.Equal(t, strconv.FormatInt(tc.userId, 10), rr.Body.String())
+				return
+			}
+
+			var resp response.Response
+
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
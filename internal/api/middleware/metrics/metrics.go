@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// unmatchedRoute labels requests that hit no route, so random paths cannot
+// blow up the label cardinality.
+const unmatchedRoute = "unmatched"
+
+// New records request counts and latencies labelled with the chi route
+// pattern (e.g. "/{alias}") rather than the raw path.
+func New(requests *prometheus.CounterVec, duration *prometheus.HistogramVec) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			t1 := time.Now()
+			next.ServeHTTP(ww, r)
+
+			route := unmatchedRoute
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			requests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+			duration.WithLabelValues(r.Method, route).Observe(time.Since(t1).Seconds())
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
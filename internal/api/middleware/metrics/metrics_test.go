@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "requests"}, []string{"method", "route", "status"})
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "duration"}, []string{"method", "route"})
+
+	router := chi.NewRouter()
+	router.Use(New(requests, duration))
+	router.Group(func(r chi.Router) {
+		r.Get("/{alias}", func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "https://example.com", http.StatusFound)
+		})
+		r.Get("/{alias}/stats", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("{}"))
+		})
+	})
+
+	for _, path := range []string{"/first", "/second", "/first/stats", "/first/stats/extra"} {
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		require.NoError(t, err)
+
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	require.Equal(t, 2.0, testutil.ToFloat64(requests.WithLabelValues(http.MethodGet, "/{alias}", "302")))
+	require.Equal(t, 1.0, testutil.ToFloat64(requests.WithLabelValues(http.MethodGet, "/{alias}/stats", "200")))
+	require.Equal(t, 1.0, testutil.ToFloat64(requests.WithLabelValues(http.MethodGet, unmatchedRoute, "404")))
+	require.Equal(t, 3, testutil.CollectAndCount(duration))
+}
@@ -1,8 +1,15 @@
 package response
 
+import "url-shortener/internal/lib/validation"
+
 type Response struct {
 	Status string `json:"status"`
-	Error  string `json:"error,omitempty"`
+	// Code is a stable, machine-readable error code; Error is for humans and
+	// may change.
+	Code  string `json:"code,omitempty"`
+	Error string `json:"error,omitempty"`
+	// Errors lists the failed fields of a VALIDATION_FAILED response.
+	Errors []validation.FieldError `json:"errors,omitempty"`
 }
 
 const (
@@ -10,15 +17,43 @@ const (
 	StatusError = "Error"
 )
 
+// Error codes, each listed with the HTTP status it is sent with.
+const (
+	CodeInvalidRequest     = "INVALID_REQUEST"     // 400
+	CodeValidationFailed   = "VALIDATION_FAILED"   // 400
+	CodeUnauthorized       = "UNAUTHORIZED"        // 401
+	CodeInvalidCredentials = "INVALID_CREDENTIALS" // 401
+	CodeForbidden          = "FORBIDDEN"           // 403
+	CodeNotFound           = "NOT_FOUND"           // 404
+	CodeMethodNotAllowed   = "METHOD_NOT_ALLOWED"  // 405
+	CodeAliasTaken         = "ALIAS_TAKEN"         // 409
+	CodeUsernameTaken      = "USERNAME_TAKEN"      // 409
+	CodeLinkExpired        = "LINK_EXPIRED"        // 410
+	CodeLinkExhausted      = "LINK_EXHAUSTED"      // 410
+	CodeBulkFailed         = "BULK_FAILED"         // 422
+	CodeInternal           = "INTERNAL_ERROR"      // 500
+	CodeUnavailable        = "UNAVAILABLE"         // 503
+)
+
 func OK() Response {
 	return Response{
 		Status: StatusOK,
 	}
 }
 
-func Error(msg string) Response {
+func Error(code string, msg string) Response {
 	return Response{
 		Status: StatusError,
+		Code:   code,
 		Error:  msg,
 	}
 }
+
+func ValidationError(errs ...validation.FieldError) Response {
+	return Response{
+		Status: StatusError,
+		Code:   CodeValidationFailed,
+		Error:  "validation failed",
+		Errors: errs,
+	}
+}
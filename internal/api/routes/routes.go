@@ -3,32 +3,140 @@ package routes
 import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"url-shortener/internal/api/handlers/bulk"
 	"url-shortener/internal/api/handlers/delete"
+	"url-shortener/internal/api/handlers/export"
+	"url-shortener/internal/api/handlers/health"
+	"url-shortener/internal/api/handlers/history"
+	"url-shortener/internal/api/handlers/ingest"
+	"url-shortener/internal/api/handlers/list"
 	"url-shortener/internal/api/handlers/login"
+	"url-shortener/internal/api/handlers/logout"
 	"url-shortener/internal/api/handlers/redirect"
 	"url-shortener/internal/api/handlers/register"
 	"url-shortener/internal/api/handlers/save"
+	"url-shortener/internal/api/handlers/stats"
+	"url-shortener/internal/api/handlers/update"
+	mwAlias "url-shortener/internal/api/middleware/alias"
+	mwAuth "url-shortener/internal/api/middleware/auth"
 	mwLogger "url-shortener/internal/api/middleware/logger"
+	mwMetrics "url-shortener/internal/api/middleware/metrics"
+	"url-shortener/internal/api/response"
+	"url-shortener/internal/config"
+	"url-shortener/internal/lib/alias"
+	"url-shortener/internal/lib/analytics"
 	"url-shortener/internal/lib/logger"
-	"url-shortener/internal/storage/sqlite"
+	"url-shortener/internal/lib/logger/handlers"
+	"url-shortener/internal/lib/metrics"
+	"url-shortener/internal/storage"
 )
 
-func Setup(log *logger.Logger, storage *sqlite.Storage) *chi.Mux {
+func Setup(
+	log *logger.Logger,
+	storage storage.Store,
+	clicks *analytics.Recorder,
+	shuttingDown *atomic.Bool,
+	m *metrics.Metrics,
+	cfg *config.Config,
+) *chi.Mux {
 	router := chi.NewRouter()
 
 	router.Use(middleware.RequestID)
+	router.Use(mwMetrics.New(m.HTTPRequests, m.HTTPRequestDuration))
 	router.Use(middleware.Recoverer)
+	// URLFormat rewrites the routing path, so it has to run before routing;
+	// in a group it would be a no-op.
 	router.Use(middleware.URLFormat)
-	router.Use(mwLogger.New(log))
 
-	// URLs
-	router.Post("/save", save.New(log, storage))
-	router.Get("/{alias}", redirect.New(log, storage))
-	router.Delete("/{alias}", delete.New(log, storage))
+	router.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, response.Error(response.CodeNotFound, "not found"))
+	})
+	router.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		render.Status(r, http.StatusMethodNotAllowed)
+		render.JSON(w, r, response.Error(response.CodeMethodNotAllowed, "method not allowed"))
+	})
 
-	// Users
-	router.Post("/register", register.New(log, storage))
-	router.Post("/login", login.New(log, storage))
+	// Probes are mounted outside the request logger so they do not flood it.
+	router.Get("/healthz", health.NewLiveness())
+	router.Get("/readyz", health.NewReadiness(log, storage, shuttingDown))
+
+	if cfg.HTTPServer.AdminAddress == "" {
+		router.Handle("/metrics", m.Handler())
+	}
+
+	aliases := alias.NewGenerator(cfg.Alias)
+	policy := alias.NewPolicy(cfg.Alias.Policy)
+
+	router.Group(func(router chi.Router) {
+		router.Use(mwLogger.New(log))
+		router.Use(mwAlias.New(policy))
+
+		// URLs
+		redirectHandler := redirect.New(log, storage, clicks, m, cfg.Redirect.Type, cfg.Redirect.CacheMaxAge)
+		router.Get("/{alias}", redirectHandler)
+		// Paths below an alias that no other route takes are passed through to
+		// links that allow it; other links answer them with 404.
+		router.Get("/{alias}/*", redirectHandler)
+
+		router.Group(func(r chi.Router) {
+			r.Use(mwAuth.New(log, storage, cfg.Session.TTL, cfg.Session.Sliding))
+
+			r.Post("/save", save.New(log, storage, aliases, policy, cfg.Alias.Attempts))
+			r.Get("/links", list.New(log, storage))
+			r.Post("/links/bulk", bulk.New(log, storage, aliases, policy, cfg.Alias.Attempts))
+			r.Get("/links/export", export.New(log, storage))
+			r.Post("/links/import", ingest.New(log, storage, aliases, policy, cfg.Alias.Attempts))
+			r.Patch("/{alias}", update.New(log, storage))
+			r.Delete("/{alias}", delete.New(log, storage))
+			r.Get("/{alias}/stats", stats.New(log, storage))
+			r.Get("/{alias}/history", history.New(log, storage))
+
+			r.Post("/logout", logout.New(log, storage))
+			r.Post("/logout-all", logout.NewAll(log, storage))
+		})
+
+		// Users
+		router.Post("/register", register.New(log, storage))
+		router.Post("/login", login.New(log, storage, cfg.Session.TTL))
+	})
+
+	reserveRoutes(policy, router)
+
+	return router
+}
+
+// reserveRoutes reserves the first path segment of every static route, so
+// that no alias is shadowed by a route.
+func reserveRoutes(policy *alias.Policy, router chi.Routes) {
+	_ = chi.Walk(router, func(_ string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		segment, _, _ := strings.Cut(strings.TrimPrefix(route, "/"), "/")
+		if segment != "" && !strings.HasPrefix(segment, "{") {
+			policy.Reserve(segment)
+		}
+		return nil
+	})
+}
+
+// NewPolicy returns the alias policy the API enforces, route words included,
+// for saving links outside of it.
+func NewPolicy(cfg *config.Config) *alias.Policy {
+	policy := alias.NewPolicy(cfg.Alias.Policy)
+	reserveRoutes(policy, Setup(handlers.NewDiscardLogger(), nil, nil, new(atomic.Bool), metrics.New(), cfg))
+	return policy
+}
+
+// SetupAdmin builds the router of the admin listener.
+func SetupAdmin(m *metrics.Metrics) *chi.Mux {
+	router := chi.NewRouter()
+
+	router.Use(middleware.Recoverer)
+
+	router.Handle("/metrics", m.Handler())
 
 	return router
 }
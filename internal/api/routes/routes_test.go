@@ -0,0 +1,93 @@
+package routes
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/lib/analytics"
+	"url-shortener/internal/lib/logger/handlers"
+	"url-shortener/internal/lib/metrics"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/memory"
+)
+
+func TestSetup_FormatExtension(t *testing.T) {
+	cfg := &config.Config{
+		Session: config.Session{TTL: time.Hour},
+		Analytics: config.Analytics{
+			QueueSize: 10,
+			Workers:   1,
+			BatchSize: 10,
+			Overflow:  config.OverflowDrop,
+		},
+		Alias: config.Alias{
+			Strategy: config.AliasStrategyRandom,
+			Length:   6,
+			Alphabet: "abcdefghijklmnopqrstuvwxyz",
+			Attempts: 3,
+			Policy: config.AliasPolicy{
+				Charset:   "abcdefghijklmnopqrstuvwxyz0123456789-_",
+				MinLength: 3,
+				MaxLength: 32,
+			},
+		},
+		Redirect: config.Redirect{Type: http.StatusFound},
+	}
+
+	store := memory.New()
+	userId, err := store.CreateUser("owner", "password")
+	require.NoError(t, err)
+	_, err = store.CreateSession(userId, "token", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	_, err = store.SaveURL("https://example.com", "alias", userId, storage.URLOptions{})
+	require.NoError(t, err)
+
+	clicks := analytics.New(handlers.NewDiscardLogger(), store, cfg.Analytics)
+	router := Setup(handlers.NewDiscardLogger(), store, clicks, new(atomic.Bool), metrics.New(), cfg)
+
+	tests := []struct {
+		name       string
+		path       string
+		token      string
+		respStatus int
+	}{
+		{
+			name:       "Redirect",
+			path:       "/alias.json",
+			respStatus: http.StatusFound,
+		},
+		{
+			name:       "Authenticated",
+			path:       "/alias/stats.json",
+			token:      "token",
+			respStatus: http.StatusOK,
+		},
+		{
+			name:       "Authenticated without token",
+			path:       "/links.json",
+			respStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(http.MethodGet, tc.path, nil)
+			require.NoError(t, err)
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.respStatus, rr.Code)
+		})
+	}
+}
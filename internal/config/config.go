@@ -1,10 +1,17 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"github.com/ilyakaznacheev/cleanenv"
 	"log"
+	"net/http"
+	"net/netip"
 	"os"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 const (
@@ -13,16 +20,154 @@ const (
 	EnvProd  = "prod"
 )
 
+const (
+	StorageDriverSQLite   = "sqlite"
+	StorageDriverMemory   = "memory"
+	StorageDriverPostgres = "postgres"
+)
+
+const (
+	OverflowDrop  = "drop"
+	OverflowBlock = "block"
+)
+
+const (
+	AliasStrategyRandom  = "random"
+	AliasStrategyID      = "id"
+	AliasStrategyHashids = "hashids"
+)
+
 type Config struct {
 	Env         string `yaml:"env" env-default:"local"`
-	StoragePath string `yaml:"storage_path" env-required:"true"`
+	StoragePath string `yaml:"storage_path"`
+	Storage     `yaml:"storage"`
 	HTTPServer  `yaml:"http_server"`
+	Session     `yaml:"session"`
+	URLExpiry   `yaml:"url_expiry"`
+	Analytics   `yaml:"analytics"`
+	Alias       `yaml:"alias"`
+	Redirect    `yaml:"redirect"`
+}
+
+type Storage struct {
+	Driver string `yaml:"driver" env-default:"sqlite"`
+	DSN    string `yaml:"dsn" env:"STORAGE_DSN"`
 }
 
 type HTTPServer struct {
 	Address     string        `yaml:"address" env-default:"localhost:8080"`
 	Timeout     time.Duration `yaml:"timeout" env-default:"4s"`
 	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
+	// ShutdownTimeout bounds how long in-flight requests may take to finish
+	// once a shutdown signal arrives.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env-default:"10s"`
+	// DrainDelay is how long the server keeps serving, with /readyz failing,
+	// after a shutdown signal, so that load balancers stop sending traffic
+	// before connections are closed.
+	DrainDelay time.Duration `yaml:"drain_delay" env-default:"5s"`
+	// AdminAddress, when set, serves /metrics on a separate listener instead
+	// of the public one.
+	AdminAddress string `yaml:"admin_address" env:"HTTP_ADMIN_ADDRESS"`
+}
+
+type Session struct {
+	TTL           time.Duration `yaml:"ttl" env-default:"720h"`
+	Sliding       bool          `yaml:"sliding" env-default:"false"`
+	SweepInterval time.Duration `yaml:"sweep_interval" env-default:"1h"`
+}
+
+// URLExpiry controls purging of expired links. Expired links keep answering
+// 410 Gone for Retention before they are deleted and their alias is freed.
+type URLExpiry struct {
+	SweepInterval time.Duration `yaml:"sweep_interval" env-default:"1h"`
+	Retention     time.Duration `yaml:"retention" env-default:"168h"`
+}
+
+// Analytics configures click recording. Clicks are queued and saved in
+// batches of BatchSize or every FlushInterval, whichever comes first. When the
+// queue is full, Overflow decides whether redirects drop the click or wait.
+type Analytics struct {
+	// IPSalt keys the visitor address digests. It is required: without a key
+	// anyone could hash every IPv4 address and reverse them. Changing it resets
+	// the unique visitor counts.
+	IPSalt        string        `yaml:"ip_salt" env:"ANALYTICS_IP_SALT" env-required:"true"`
+	QueueSize     int           `yaml:"queue_size" env-default:"10000"`
+	Workers       int           `yaml:"workers" env-default:"1"`
+	BatchSize     int           `yaml:"batch_size" env-default:"200"`
+	FlushInterval time.Duration `yaml:"flush_interval" env-default:"1s"`
+	Overflow      string        `yaml:"overflow" env-default:"drop"`
+	// TrustedProxies lists the addresses or CIDR ranges of the proxies in
+	// front of the server. Visitor addresses are taken from X-Forwarded-For
+	// only on connections from them.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+// TrustedPrefixes returns TrustedProxies as prefixes, a single address being
+// a prefix of its full length. Load has checked that they parse.
+func (a Analytics) TrustedPrefixes() []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(a.TrustedProxies))
+	for _, proxy := range a.TrustedProxies {
+		if prefix, err := parseProxy(proxy); err == nil {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+
+	return prefixes
+}
+
+func parseProxy(proxy string) (netip.Prefix, error) {
+	if strings.Contains(proxy, "/") {
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(proxy)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// Alias configures the aliases generated for links saved without one.
+// Strategy is "random", "id" (the row id in base len(Alphabet)) or "hashids"
+// (the row id obfuscated with Salt). Length is the minimum length for the id
+// based strategies. A generated alias that is already taken is regenerated up
+// to Attempts times.
+type Alias struct {
+	Strategy string `yaml:"strategy" env-default:"random"`
+	Length   int    `yaml:"length" env-default:"6"`
+	Alphabet string `yaml:"alphabet" env-default:"ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"`
+	Salt     string `yaml:"salt" env:"ALIAS_SALT"`
+	Attempts int    `yaml:"attempts" env-default:"5"`
+
+	Policy AliasPolicy `yaml:"policy"`
+}
+
+// Redirect configures the redirect responses. Type is the status of links
+// that do not set their own. Permanent redirects (301 and 308) may be cached
+// by clients for CacheMaxAge, or until the link expires if that is sooner, so
+// their clicks go uncounted; temporary ones, and those of links with a click
+// limit, are never cached.
+type Redirect struct {
+	Type        int           `yaml:"type" env-default:"302"`
+	CacheMaxAge time.Duration `yaml:"cache_max_age" env-default:"24h"`
+}
+
+// AliasPolicy restricts the aliases links may get. Aliases may only use
+// characters of Charset. Reserved words come on top of the first path segments
+// of the server's own routes, which are always reserved. With CaseInsensitive
+// on, aliases are stored and looked up in lower case.
+type AliasPolicy struct {
+	Charset         string   `yaml:"charset" env-default:"ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"`
+	MinLength       int      `yaml:"min_length" env-default:"3"`
+	MaxLength       int      `yaml:"max_length" env-default:"64"`
+	CaseInsensitive bool     `yaml:"case_insensitive"`
+	Reserved        []string `yaml:"reserved"`
 }
 
 func Load() *Config {
@@ -35,11 +180,127 @@ func Load() *Config {
 		log.Fatal("CONFIG_PATH does not exist")
 	}
 
+	cfg, err := load(configPath)
+	if err != nil {
+		log.Fatal("Can't read config:", err)
+	}
+
+	switch cfg.Storage.Driver {
+	case StorageDriverSQLite:
+		if cfg.StoragePath == "" {
+			log.Fatal("storage_path is required for the sqlite driver")
+		}
+	case StorageDriverPostgres:
+		if cfg.Storage.DSN == "" {
+			log.Fatal("storage.dsn is required for the postgres driver")
+		}
+	case StorageDriverMemory:
+	default:
+		log.Fatalf("unknown storage driver %q", cfg.Storage.Driver)
+	}
+
+	if cfg.HTTPServer.DrainDelay < 0 {
+		log.Fatal("http_server drain_delay must not be negative")
+	}
+
+	if cfg.Session.TTL <= 0 || cfg.Session.SweepInterval <= 0 {
+		log.Fatal("session ttl and sweep_interval must be positive")
+	}
+
+	if cfg.URLExpiry.SweepInterval <= 0 {
+		log.Fatal("url_expiry sweep_interval must be positive")
+	}
+	if cfg.URLExpiry.Retention < 0 {
+		log.Fatal("url_expiry retention must not be negative")
+	}
+
+	if cfg.Analytics.QueueSize < 1 || cfg.Analytics.Workers < 1 || cfg.Analytics.BatchSize < 1 {
+		log.Fatal("analytics queue_size, workers and batch_size must be positive")
+	}
+	if cfg.Analytics.FlushInterval <= 0 {
+		log.Fatal("analytics flush_interval must be positive")
+	}
+	if cfg.Analytics.Overflow != OverflowDrop && cfg.Analytics.Overflow != OverflowBlock {
+		log.Fatalf("unknown analytics overflow policy %q", cfg.Analytics.Overflow)
+	}
+	for _, proxy := range cfg.Analytics.TrustedProxies {
+		if _, err := parseProxy(proxy); err != nil {
+			log.Fatalf("invalid analytics trusted proxy %q: %v", proxy, err)
+		}
+	}
+
+	switch cfg.Redirect.Type {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		log.Fatalf("redirect type must be 301, 302, 307 or 308, got %d", cfg.Redirect.Type)
+	}
+	if cfg.Redirect.CacheMaxAge < 0 {
+		log.Fatal("redirect cache_max_age must not be negative")
+	}
+
+	switch cfg.Alias.Strategy {
+	case AliasStrategyHashids:
+		if cfg.Alias.Salt == "" {
+			log.Fatal("alias.salt is required for the hashids strategy")
+		}
+		if utf8.RuneCountInString(cfg.Alias.Alphabet) < 3 {
+			log.Fatal("the hashids strategy needs an alias alphabet of at least 3 characters")
+		}
+	case AliasStrategyRandom, AliasStrategyID:
+	default:
+		log.Fatalf("unknown alias strategy %q", cfg.Alias.Strategy)
+	}
+	if cfg.Alias.Length < 1 || cfg.Alias.Attempts < 1 {
+		log.Fatal("alias length and attempts must be positive")
+	}
+	if err := checkAlphabet(cfg.Alias.Alphabet); err != nil {
+		log.Fatalf("invalid alias alphabet: %v", err)
+	}
+
+	policy := cfg.Alias.Policy
+	if policy.MinLength < 1 || policy.MaxLength < policy.MinLength {
+		log.Fatal("alias policy needs 0 < min_length <= max_length")
+	}
+	if cfg.Alias.Length < policy.MinLength || cfg.Alias.Length > policy.MaxLength {
+		log.Fatal("alias length must be within the alias policy min_length and max_length")
+	}
+	// Generated aliases must pass the policy too.
+	for _, r := range cfg.Alias.Alphabet {
+		if !strings.ContainsRune(policy.Charset, r) {
+			log.Fatalf("alias alphabet character %q is not in the alias policy charset", r)
+		}
+		if policy.CaseInsensitive && unicode.IsUpper(r) {
+			log.Fatal("alias alphabet must be lower case when aliases are case insensitive")
+		}
+	}
+
+	return cfg
+}
+
+// load reads the config file, with the environment taking precedence, and
+// checks that required settings are present.
+func load(configPath string) (*Config, error) {
 	var cfg Config
 
 	if err := cleanenv.ReadConfig(configPath, &cfg); err != nil {
-		log.Fatal("Can't read config:", err)
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+func checkAlphabet(alphabet string) error {
+	if utf8.RuneCountInString(alphabet) < 2 {
+		return errors.New("at least 2 characters are required")
+	}
+
+	seen := make(map[rune]bool)
+	for _, r := range alphabet {
+		if seen[r] {
+			return fmt.Errorf("duplicate character %q", r)
+		}
+		seen[r] = true
 	}
 
-	return &cfg
+	return nil
 }
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad_IPSalt(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    string
+		env       string
+		respError string
+	}{
+		{
+			name:   "From file",
+			config: "analytics:\n  ip_salt: secret\n",
+		},
+		{
+			name:   "From environment",
+			config: "analytics: {}\n",
+			env:    "secret",
+		},
+		{
+			name:      "Missing",
+			config:    "analytics: {}\n",
+			respError: "IPSalt",
+		},
+		{
+			name:      "Empty",
+			config:    "analytics:\n  ip_salt: \"\"\n",
+			respError: "IPSalt",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			// An empty variable would still override the file, so unset it.
+			t.Setenv("ANALYTICS_IP_SALT", tc.env)
+			if tc.env == "" {
+				require.NoError(t, os.Unsetenv("ANALYTICS_IP_SALT"))
+			}
+
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tc.config), 0o600))
+
+			cfg, err := load(path)
+			if tc.respError != "" {
+				require.ErrorContains(t, err, tc.respError)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, "secret", cfg.Analytics.IPSalt)
+		})
+	}
+}
@@ -0,0 +1,302 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatBitly  = "bitly"
+	FormatYOURLS = "yourls"
+	FormatShlink = "shlink"
+)
+
+var ErrUnknownFormat = errors.New("unknown format")
+
+// columns lists the header names each field may go by, normalized by
+// headerKey. The first column present wins.
+type columns struct {
+	alias, url, created, clicks, tags []string
+	// shortURL marks an alias column holding a whole short URL rather than
+	// the alias alone.
+	shortURL bool
+}
+
+var csvColumns = map[string]columns{
+	FormatBitly: {
+		alias:    []string{"custombitlink", "bitlink", "link", "shorturl", "shortlink"},
+		url:      []string{"longurl", "destination", "originalurl"},
+		created:  []string{"createdat", "created", "datecreated"},
+		clicks:   []string{"clicks", "totalclicks", "engagements"},
+		tags:     []string{"tags"},
+		shortURL: true,
+	},
+	FormatYOURLS: {
+		alias:   []string{"keyword"},
+		url:     []string{"url"},
+		created: []string{"timestamp"},
+		clicks:  []string{"clicks"},
+	},
+	FormatShlink: {
+		alias:   []string{"shortcode"},
+		url:     []string{"longurl"},
+		created: []string{"datecreated", "createdat"},
+		clicks:  []string{"visits", "visitscount"},
+		tags:    []string{"tags"},
+	},
+}
+
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// Parse reads an export in the given format. Entries that cannot be read are
+// returned with Err set; the error is for input that cannot be read at all.
+func Parse(r io.Reader, format string) ([]Entry, error) {
+	cols, ok := csvColumns[format]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+
+	// Shlink exports its API listing as JSON and its dashboard table as CSV.
+	if format == FormatShlink {
+		br := bufio.NewReader(r)
+		if first, err := peekNonSpace(br); err == nil && (first == '{' || first == '[') {
+			return parseShlinkJSON(br)
+		}
+		r = br
+	}
+
+	return parseCSV(r, cols)
+}
+
+// peekNonSpace returns the first byte after any byte order mark and leading
+// whitespace, without consuming it.
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	if bom, _ := br.Peek(3); string(bom) == "\uFEFF" {
+		_, _ = br.Discard(3)
+	}
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b == ' ' || b == '\t' || b == '\r' || b == '\n' {
+			continue
+		}
+		return b, br.UnreadByte()
+	}
+}
+
+func parseCSV(r io.Reader, cols columns) ([]Entry, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("header: %w", err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		key := headerKey(name)
+		if _, ok := index[key]; !ok {
+			index[key] = i
+		}
+	}
+
+	find := func(names []string) int {
+		for _, name := range names {
+			if i, ok := index[name]; ok {
+				return i
+			}
+		}
+		return -1
+	}
+
+	aliasCol, urlCol := find(cols.alias), find(cols.url)
+	createdCol, clicksCol, tagsCol := find(cols.created), find(cols.clicks), find(cols.tags)
+	if urlCol < 0 {
+		return nil, fmt.Errorf("header: no %s column", cols.url[0])
+	}
+
+	var entries []Entry
+	for n := 1; ; n++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			entries = append(entries, Entry{N: n, Err: err})
+			continue
+		}
+		if err != nil {
+			return entries, err
+		}
+
+		field := func(col int) string {
+			if col < 0 || col >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[col])
+		}
+
+		e := Entry{N: n}
+		e.Alias = field(aliasCol)
+		if cols.shortURL {
+			e.Alias = aliasFromShortURL(e.Alias)
+		}
+		e.URL = field(urlCol)
+		e.Tags = splitTags(field(tagsCol))
+
+		if e.CreatedAt, err = parseTime(field(createdCol)); err != nil {
+			e.Err = err
+		} else if e.Clicks, err = parseClicks(field(clicksCol)); err != nil {
+			e.Err = err
+		}
+
+		entries = append(entries, e)
+	}
+}
+
+type shlinkURL struct {
+	ShortCode     string `json:"shortCode"`
+	LongURL       string `json:"longUrl"`
+	DateCreated   string `json:"dateCreated"`
+	VisitsCount   *int64 `json:"visitsCount"`
+	VisitsSummary *struct {
+		Total int64 `json:"total"`
+	} `json:"visitsSummary"`
+	Tags []string `json:"tags"`
+}
+
+// parseShlinkJSON accepts a bare list of short URLs as well as the
+// {"shortUrls": {"data": [...]}} and {"data": [...]} API responses.
+func parseShlinkJSON(r io.Reader) ([]Entry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []json.RawMessage
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		err = json.Unmarshal(data, &items)
+	} else {
+		var page struct {
+			ShortURLs struct {
+				Data []json.RawMessage `json:"data"`
+			} `json:"shortUrls"`
+			Data []json.RawMessage `json:"data"`
+		}
+		err = json.Unmarshal(data, &page)
+		items = page.ShortURLs.Data
+		if items == nil {
+			items = page.Data
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid json: %w", err)
+	}
+
+	entries := make([]Entry, 0, len(items))
+	for i, item := range items {
+		e := Entry{N: i + 1}
+
+		var u shlinkURL
+		if err := json.Unmarshal(item, &u); err != nil {
+			e.Err = fmt.Errorf("invalid json: %w", err)
+			entries = append(entries, e)
+			continue
+		}
+
+		e.Alias = u.ShortCode
+		e.URL = u.LongURL
+		e.Tags = u.Tags
+		switch {
+		case u.VisitsSummary != nil:
+			e.Clicks = u.VisitsSummary.Total
+		case u.VisitsCount != nil:
+			e.Clicks = *u.VisitsCount
+		}
+		e.CreatedAt, e.Err = parseTime(u.DateCreated)
+
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+func headerKey(name string) string {
+	name = strings.TrimPrefix(name, "\uFEFF")
+	name = strings.ToLower(strings.TrimSpace(name))
+	return strings.NewReplacer(" ", "", "_", "", "-", "").Replace(name)
+}
+
+// aliasFromShortURL returns the path of a short URL such as bit.ly/abc or
+// https://bit.ly/abc. Values without a host are taken as the alias itself.
+func aliasFromShortURL(short string) string {
+	if short == "" {
+		return ""
+	}
+	if !strings.Contains(short, "://") {
+		short = "https://" + short
+	}
+
+	u, err := url.Parse(short)
+	if err != nil || u.Path == "" || u.Path == "/" {
+		return strings.TrimPrefix(short, "https://")
+	}
+
+	return path.Base(u.Path)
+}
+
+func splitTags(s string) []string {
+	var tags []string
+	for _, tag := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' || r == '|' }) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid created time %q", s)
+}
+
+func parseClicks(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	clicks, err := strconv.ParseInt(strings.ReplaceAll(s, ",", ""), 10, 64)
+	if err != nil || clicks < 0 {
+		return 0, fmt.Errorf("invalid clicks %q", s)
+	}
+	return clicks, nil
+}
@@ -0,0 +1,105 @@
+package importer
+
+import (
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	created := time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		format  string
+		input   string
+		entries []Entry
+		errs    []string
+		err     string
+	}{
+		{
+			name:   "Bitly",
+			format: FormatBitly,
+			input: "\uFEFFBitlink,Long URL,Created At,Total Clicks,Tags\n" +
+				"bit.ly/abc,https://example.com/a,2023-04-05T06:07:08Z,\"1,204\",\"news, tech\"\n" +
+				"https://bit.ly/def,https://example.com/b,,,\n",
+			entries: []Entry{
+				{N: 1, Link: Link{Alias: "abc", URL: "https://example.com/a", CreatedAt: created, Clicks: 1204, Tags: []string{"news", "tech"}}},
+				{N: 2, Link: Link{Alias: "def", URL: "https://example.com/b"}},
+			},
+		},
+		{
+			name:   "YOURLS",
+			format: FormatYOURLS,
+			input: "keyword,url,title,timestamp,ip,clicks\n" +
+				"abc,https://example.com/a,A,2023-04-05 06:07:08,127.0.0.1,3\n" +
+				"def,https://example.com/b,B,yesterday,127.0.0.1,1\n",
+			entries: []Entry{
+				{N: 1, Link: Link{Alias: "abc", URL: "https://example.com/a", CreatedAt: created, Clicks: 3}},
+				{N: 2, Link: Link{Alias: "def", URL: "https://example.com/b"}},
+			},
+			errs: []string{"", `invalid created time "yesterday"`},
+		},
+		{
+			name:   "Shlink JSON",
+			format: FormatShlink,
+			input: `{"shortUrls": {"data": [
+				{"shortCode": "abc", "longUrl": "https://example.com/a", "dateCreated": "2023-04-05T08:07:08+02:00", "visitsSummary": {"total": 7}, "tags": ["news"]},
+				{"shortCode": "def", "longUrl": "https://example.com/b", "visitsCount": 2}
+			]}}`,
+			entries: []Entry{
+				{N: 1, Link: Link{Alias: "abc", URL: "https://example.com/a", CreatedAt: created, Clicks: 7, Tags: []string{"news"}}},
+				{N: 2, Link: Link{Alias: "def", URL: "https://example.com/b", Clicks: 2}},
+			},
+		},
+		{
+			name:   "Shlink CSV",
+			format: FormatShlink,
+			input: "Short code,Long URL,Date created,Visits,Tags\n" +
+				"abc,https://example.com/a,2023-04-05,x,\n",
+			entries: []Entry{
+				{N: 1, Link: Link{Alias: "abc", URL: "https://example.com/a", CreatedAt: time.Date(2023, 4, 5, 0, 0, 0, 0, time.UTC)}},
+			},
+			errs: []string{`invalid clicks "x"`},
+		},
+		{
+			name:   "Missing url column",
+			format: FormatYOURLS,
+			input:  "keyword,clicks\nabc,1\n",
+			err:    "header: no url column",
+		},
+		{
+			name:   "Unknown format",
+			format: "tinyurl",
+			err:    `unknown format: "tinyurl"`,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			entries, err := Parse(strings.NewReader(tc.input), tc.format)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, entries, len(tc.entries))
+
+			for i, e := range entries {
+				if tc.errs != nil && tc.errs[i] != "" {
+					require.EqualError(t, e.Err, tc.errs[i])
+				} else {
+					require.NoError(t, e.Err)
+				}
+				e.Err = nil
+				e.CreatedAt = e.CreatedAt.UTC()
+				require.Equal(t, tc.entries[i], e)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+// Package importer moves the links exported by other URL shorteners into our
+// storage.
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+	"url-shortener/internal/api/handlers/save"
+	"url-shortener/internal/lib/alias"
+	"url-shortener/internal/lib/validation"
+	"url-shortener/internal/storage"
+)
+
+// Link is a link as read from an export.
+type Link struct {
+	Alias     string
+	URL       string `json:"url" validate:"required,url"`
+	CreatedAt time.Time
+	Clicks    int64    `json:"clicks" validate:"gte=0"`
+	Tags      []string `json:"tags" validate:"max=10,dive,required,max=32,excludesall=0x2C"`
+}
+
+// Entry is one link of an export, numbered from 1 in file order. Err is set
+// when the entry could not be read.
+type Entry struct {
+	Link
+	N   int
+	Err error
+}
+
+// Result tells what became of an entry. Links whose own alias could not be
+// kept are saved under NewAlias; Reason says why, or why the entry was
+// skipped.
+type Result struct {
+	Entry    int
+	Alias    string
+	URL      string
+	NewAlias string
+	Skipped  bool
+	Reason   string
+}
+
+type Store interface {
+	NextURLID() (int64, error)
+	SaveURL(urlToSave string, alias string, userId int64, opts storage.URLOptions) (int64, error)
+}
+
+type Importer struct {
+	store    Store
+	aliases  alias.Generator
+	policy   *alias.Policy
+	attempts int
+}
+
+func New(store Store, aliases alias.Generator, policy *alias.Policy, attempts int) *Importer {
+	return &Importer{
+		store:    store,
+		aliases:  aliases,
+		policy:   policy,
+		attempts: attempts,
+	}
+}
+
+// Import saves the entries for userId. Entries that cannot be saved are
+// skipped; only a storage failure stops the import, returning the results
+// so far.
+func (im *Importer) Import(entries []Entry, userId int64) ([]Result, error) {
+	results := make([]Result, 0, len(entries))
+
+	for _, e := range entries {
+		res, err := im.importEntry(e, userId)
+		if err != nil {
+			return results, fmt.Errorf("entry %d: %w", e.N, err)
+		}
+		results = append(results, res)
+	}
+
+	return results, nil
+}
+
+func (im *Importer) importEntry(e Entry, userId int64) (Result, error) {
+	res := Result{Entry: e.N, Alias: e.Alias, URL: e.URL}
+
+	skip := func(reason string) (Result, error) {
+		res.Skipped = true
+		res.Reason = reason
+		return res, nil
+	}
+
+	if e.Err != nil {
+		return skip(e.Err.Error())
+	}
+	if errs := validation.Struct(e.Link); errs != nil {
+		return skip(errs[0].Message)
+	}
+
+	opts := storage.URLOptions{
+		CreatedAt: e.CreatedAt,
+		Clicks:    e.Clicks,
+		Tags:      e.Tags,
+	}
+
+	original := im.policy.Normalize(e.Alias)
+	if original != "" {
+		fieldErr := im.policy.Check(original)
+		if fieldErr == nil {
+			_, err := im.store.SaveURL(e.URL, original, userId, opts)
+			if errors.Is(err, storage.ErrURLExists) {
+				return skip("alias already exists")
+			}
+			return res, err
+		}
+
+		res.Reason = fieldErr.Message
+	}
+
+	newAlias, _, err := save.SaveGenerated(im.store, im.aliases, im.policy, im.attempts, e.URL, userId, opts)
+	if errors.Is(err, save.ErrNoFreeAlias) {
+		return skip(err.Error())
+	}
+	if err != nil {
+		return res, err
+	}
+
+	if original != "" {
+		res.NewAlias = newAlias
+	}
+
+	return res, nil
+}
+
+// WriteReport writes the skipped and renamed entries as CSV.
+func WriteReport(w io.Writer, results []Result) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write([]string{"entry", "alias", "url", "status", "new_alias", "reason"}); err != nil {
+		return err
+	}
+
+	for _, res := range results {
+		status := "renamed"
+		if res.Skipped {
+			status = "skipped"
+		} else if res.NewAlias == "" {
+			continue
+		}
+
+		err := cw.Write([]string{strconv.Itoa(res.Entry), res.Alias, res.URL, status, res.NewAlias, res.Reason})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
@@ -0,0 +1,86 @@
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"url-shortener/internal/config"
+	"url-shortener/internal/lib/alias"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/memory"
+)
+
+const testUserId int64 = 1
+
+var aliasConfig = config.Alias{
+	Strategy: config.AliasStrategyID,
+	Length:   6,
+	Alphabet: "abcdefghijklmnopqrstuvwxyz",
+	Attempts: 3,
+	Policy: config.AliasPolicy{
+		Charset:   "abcdefghijklmnopqrstuvwxyz_",
+		MinLength: 3,
+		MaxLength: 32,
+		Reserved:  []string{"links"},
+	},
+}
+
+func TestImport(t *testing.T) {
+	store := memory.New()
+	_, err := store.SaveURL("https://example.com/taken", "taken", testUserId, storage.URLOptions{})
+	require.NoError(t, err)
+
+	im := New(store, alias.NewGenerator(aliasConfig), alias.NewPolicy(aliasConfig.Policy), aliasConfig.Attempts)
+
+	entries := []Entry{
+		{N: 1, Link: Link{Alias: "kept", URL: "https://example.com/1", Clicks: 5, Tags: []string{"news"}}},
+		{N: 2, Link: Link{Alias: "links", URL: "https://example.com/2"}},
+		{N: 3, Link: Link{Alias: "taken", URL: "https://example.com/3"}},
+		{N: 4, Link: Link{Alias: "bad", URL: "not a url"}},
+		{N: 5, Err: errors.New("invalid clicks \"x\"")},
+		{N: 6, Link: Link{URL: "https://example.com/6"}},
+	}
+
+	results, err := im.Import(entries, testUserId)
+	require.NoError(t, err)
+	require.Len(t, results, len(entries))
+
+	require.Equal(t, Result{Entry: 1, Alias: "kept", URL: "https://example.com/1"}, results[0])
+
+	require.False(t, results[1].Skipped)
+	require.NotEmpty(t, results[1].NewAlias)
+	require.Equal(t, `alias "links" is reserved`, results[1].Reason)
+
+	require.True(t, results[2].Skipped)
+	require.Equal(t, "alias already exists", results[2].Reason)
+
+	require.True(t, results[3].Skipped)
+	require.True(t, results[4].Skipped)
+	require.Equal(t, `invalid clicks "x"`, results[4].Reason)
+
+	require.False(t, results[5].Skipped)
+	require.Empty(t, results[5].NewAlias)
+
+	links, err := store.ListURLs(testUserId, storage.URLQuery{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, links, 4)
+
+	var kept storage.URLInfo
+	for _, link := range links {
+		if link.Alias == "kept" {
+			kept = link
+		}
+	}
+	require.Equal(t, int64(5), kept.Clicks)
+	require.Equal(t, []string{"news"}, kept.Tags)
+
+	var report bytes.Buffer
+	require.NoError(t, WriteReport(&report, results))
+	require.Equal(t, "entry,alias,url,status,new_alias,reason\n"+
+		"2,links,https://example.com/2,renamed,"+results[1].NewAlias+",\"alias \"\"links\"\" is reserved\"\n"+
+		"3,taken,https://example.com/3,skipped,,alias already exists\n"+
+		"4,bad,not a url,skipped,,"+results[3].Reason+"\n"+
+		"5,,,skipped,,\"invalid clicks \"\"x\"\"\"\n",
+		report.String())
+}
@@ -0,0 +1,153 @@
+// Package alias generates short link aliases.
+package alias
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"url-shortener/internal/config"
+)
+
+var errNegativeID = errors.New("id must not be negative")
+
+// Generator produces candidate aliases. id is the row id reserved for the new
+// link; strategies that don't derive the alias from it ignore it.
+type Generator interface {
+	Generate(id int64) (string, error)
+}
+
+func NewGenerator(cfg config.Alias) Generator {
+	alphabet := []rune(cfg.Alphabet)
+
+	switch cfg.Strategy {
+	case config.AliasStrategyID:
+		return ID{alphabet: alphabet, length: cfg.Length}
+	case config.AliasStrategyHashids:
+		return NewHashids(alphabet, cfg.Length, cfg.Salt)
+	default:
+		return Random{alphabet: alphabet, length: cfg.Length}
+	}
+}
+
+// Random picks every character independently from crypto/rand.
+type Random struct {
+	alphabet []rune
+	length   int
+}
+
+func (g Random) Generate(int64) (string, error) {
+	base := big.NewInt(int64(len(g.alphabet)))
+
+	b := make([]rune, g.length)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, base)
+		if err != nil {
+			return "", err
+		}
+		b[i] = g.alphabet[n.Int64()]
+	}
+
+	return string(b), nil
+}
+
+// ID encodes the row id in the alphabet, left-padded to length. Aliases are
+// short and unique, but reveal how many links exist.
+type ID struct {
+	alphabet []rune
+	length   int
+}
+
+func (g ID) Generate(id int64) (string, error) {
+	if id < 0 {
+		return "", errNegativeID
+	}
+
+	return encode(id, g.alphabet, g.length), nil
+}
+
+// Hashids obfuscates the row id in the manner of hashids: the alphabet is
+// shuffled with a secret salt and reshuffled per id, so that consecutive ids
+// give unrelated aliases.
+type Hashids struct {
+	alphabet []rune
+	length   int
+	salt     []rune
+}
+
+func NewHashids(alphabet []rune, length int, salt string) Hashids {
+	saltRunes := []rune(salt)
+
+	return Hashids{
+		alphabet: shuffle(alphabet, saltRunes),
+		length:   length,
+		salt:     saltRunes,
+	}
+}
+
+func (g Hashids) Generate(id int64) (string, error) {
+	if id < 0 {
+		return "", errNegativeID
+	}
+
+	// The lottery character leads the alias and seeds the per-id alphabet.
+	// The first character of that alphabet is kept out of the digits and
+	// only marks where the padding starts, so the encoding stays unique.
+	lottery := g.alphabet[id%int64(len(g.alphabet))]
+	alphabet := shuffle(g.alphabet, append([]rune{lottery}, g.salt...))
+	guard, digits := alphabet[0], alphabet[1:]
+
+	res := []rune(string(lottery) + encode(id, digits, 0))
+	if len(res) < g.length {
+		res = append(res, guard)
+		for pad := shuffle(digits, res); len(res) < g.length; pad = shuffle(pad, g.salt) {
+			res = append(res, pad[:min(len(pad), g.length-len(res))]...)
+		}
+	}
+
+	return string(res), nil
+}
+
+// encode writes n in base len(alphabet), left-padded with the zero digit to
+// at least length characters.
+func encode(n int64, alphabet []rune, length int) string {
+	base := int64(len(alphabet))
+
+	var digits []rune
+	for {
+		digits = append(digits, alphabet[n%base])
+		n /= base
+		if n == 0 {
+			break
+		}
+	}
+	for len(digits) < length {
+		digits = append(digits, alphabet[0])
+	}
+
+	for i, j := 0, len(digits)-1; i < j; i, j = i+1, j-1 {
+		digits[i], digits[j] = digits[j], digits[i]
+	}
+
+	return string(digits)
+}
+
+// shuffle is the salted, deterministic shuffle of hashids.
+func shuffle(alphabet []rune, salt []rune) []rune {
+	res := make([]rune, len(alphabet))
+	copy(res, alphabet)
+
+	if len(salt) == 0 {
+		return res
+	}
+
+	for i, v, p := len(res)-1, 0, 0; i > 0; i-- {
+		v %= len(salt)
+		n := int(salt[v])
+		p += n
+		j := (n + v + p) % i
+		res[i], res[j] = res[j], res[i]
+		v++
+	}
+
+	return res
+}
@@ -0,0 +1,83 @@
+package alias
+
+import (
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"url-shortener/internal/config"
+)
+
+const base62 = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+func TestNewGenerator(t *testing.T) {
+	cfg := config.Alias{Alphabet: base62, Length: 6, Salt: "salt"}
+
+	for strategy, want := range map[string]Generator{
+		config.AliasStrategyRandom:  Random{},
+		config.AliasStrategyID:      ID{},
+		config.AliasStrategyHashids: Hashids{},
+	} {
+		cfg.Strategy = strategy
+		require.IsType(t, want, NewGenerator(cfg), strategy)
+	}
+}
+
+func TestRandom(t *testing.T) {
+	g := Random{alphabet: []rune("ab"), length: 8}
+
+	for i := 0; i < 100; i++ {
+		alias, err := g.Generate(0)
+		require.NoError(t, err)
+		require.Len(t, alias, 8)
+		require.Empty(t, strings.Trim(alias, "ab"))
+	}
+}
+
+func TestID(t *testing.T) {
+	g := ID{alphabet: []rune(base62), length: 3}
+
+	for id, want := range map[int64]string{
+		0:            "AAA",
+		1:            "AAB",
+		62:           "ABA",
+		62 * 62 * 62: "BAAA",
+	} {
+		alias, err := g.Generate(id)
+		require.NoError(t, err)
+		require.Equal(t, want, alias)
+	}
+
+	_, err := g.Generate(-1)
+	require.Error(t, err)
+}
+
+func TestHashids(t *testing.T) {
+	g := NewHashids([]rune(base62), 6, "salt")
+
+	seen := make(map[string]int64)
+	for id := int64(0); id < 20000; id++ {
+		alias, err := g.Generate(id)
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, len(alias), 6)
+
+		other, ok := seen[alias]
+		require.False(t, ok, "ids %d and %d both give %q", id, other, alias)
+		seen[alias] = id
+	}
+
+	first, _ := g.Generate(42)
+	again, _ := g.Generate(42)
+	require.Equal(t, first, again)
+
+	salted, _ := NewHashids([]rune(base62), 6, "pepper").Generate(42)
+	require.NotEqual(t, first, salted)
+
+	next, _ := g.Generate(43)
+	require.NotEqual(t, first[1:], next[1:])
+
+	short := NewHashids([]rune("abc"), 4, "salt")
+	for id := int64(0); id < 100; id++ {
+		_, err := short.Generate(id)
+		require.NoError(t, err)
+	}
+}
@@ -0,0 +1,78 @@
+package alias
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+	"url-shortener/internal/config"
+	"url-shortener/internal/lib/validation"
+)
+
+// Policy decides which aliases may be used.
+type Policy struct {
+	charset         string
+	minLength       int
+	maxLength       int
+	caseInsensitive bool
+	reserved        map[string]bool
+}
+
+func NewPolicy(cfg config.AliasPolicy) *Policy {
+	p := &Policy{
+		charset:         cfg.Charset,
+		minLength:       cfg.MinLength,
+		maxLength:       cfg.MaxLength,
+		caseInsensitive: cfg.CaseInsensitive,
+		reserved:        make(map[string]bool),
+	}
+
+	p.Reserve(cfg.Reserved...)
+
+	return p
+}
+
+// Reserve forbids words as aliases, whatever their case. It must not be
+// called once the policy is in use.
+func (p *Policy) Reserve(words ...string) {
+	for _, word := range words {
+		p.reserved[strings.ToLower(word)] = true
+	}
+}
+
+// Normalize returns the form an alias is stored and looked up under.
+func (p *Policy) Normalize(alias string) string {
+	if p.caseInsensitive {
+		return strings.ToLower(alias)
+	}
+
+	return alias
+}
+
+// Check reports why a normalized alias may not be used, or returns nil.
+func (p *Policy) Check(alias string) *validation.FieldError {
+	if n := utf8.RuneCountInString(alias); n < p.minLength {
+		return violation("min", fmt.Sprintf("alias must be at least %d characters long", p.minLength))
+	} else if n > p.maxLength {
+		return violation("max", fmt.Sprintf("alias must be at most %d characters long", p.maxLength))
+	}
+
+	for _, r := range alias {
+		if !strings.ContainsRune(p.charset, r) {
+			return violation("charset", fmt.Sprintf("alias must not contain %q", r))
+		}
+	}
+
+	if p.reserved[strings.ToLower(alias)] {
+		return violation("reserved", fmt.Sprintf("alias %q is reserved", alias))
+	}
+
+	return nil
+}
+
+func violation(rule string, msg string) *validation.FieldError {
+	return &validation.FieldError{
+		Field:   "alias",
+		Rule:    rule,
+		Message: msg,
+	}
+}
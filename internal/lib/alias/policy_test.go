@@ -0,0 +1,55 @@
+package alias
+
+import (
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"url-shortener/internal/config"
+)
+
+func TestPolicy_Check(t *testing.T) {
+	cfg := config.AliasPolicy{
+		Charset:   base62 + "-_",
+		MinLength: 3,
+		MaxLength: 10,
+		Reserved:  []string{"admin"},
+	}
+
+	p := NewPolicy(cfg)
+	p.Reserve("save", "Login")
+
+	tests := []struct {
+		alias string
+		rule  string
+	}{
+		{alias: "my-link_1"},
+		{alias: "ab", rule: "min"},
+		{alias: strings.Repeat("a", 11), rule: "max"},
+		{alias: "a/b/c", rule: "charset"},
+		{alias: "héllo", rule: "charset"},
+		{alias: "admin", rule: "reserved"},
+		{alias: "SAVE", rule: "reserved"},
+		{alias: "login", rule: "reserved"},
+	}
+
+	for _, tc := range tests {
+		err := p.Check(tc.alias)
+		if tc.rule == "" {
+			require.Nil(t, err, tc.alias)
+			continue
+		}
+
+		require.NotNil(t, err, tc.alias)
+		require.Equal(t, "alias", err.Field)
+		require.Equal(t, tc.rule, err.Rule, tc.alias)
+		require.NotEmpty(t, err.Message)
+	}
+}
+
+func TestPolicy_Normalize(t *testing.T) {
+	cfg := config.AliasPolicy{}
+	require.Equal(t, "MyLink", NewPolicy(cfg).Normalize("MyLink"))
+
+	cfg.CaseInsensitive = true
+	require.Equal(t, "mylink", NewPolicy(cfg).Normalize("MyLink"))
+}
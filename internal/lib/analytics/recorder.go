@@ -0,0 +1,203 @@
+package analytics
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/lib/logger"
+	"url-shortener/internal/lib/security"
+	"url-shortener/internal/storage"
+)
+
+type ClickSaver interface {
+	SaveClicks(clicks []storage.Click) error
+}
+
+// Recorder turns redirects into click records and queues them for a pool of
+// workers that save them in batches, so the redirect itself never waits for
+// the database.
+type Recorder struct {
+	log   *logger.Logger
+	saver ClickSaver
+	cfg   config.Analytics
+	// trusted holds the proxies whose X-Forwarded-For is believed.
+	trusted []netip.Prefix
+
+	events chan storage.Click
+	// mu guards stopped: Record holds it for reading while it sends so that
+	// Stop never closes the queue under a pending send.
+	mu      sync.RWMutex
+	stopped bool
+	wg      sync.WaitGroup
+
+	dropped atomic.Int64
+}
+
+func New(log *logger.Logger, saver ClickSaver, cfg config.Analytics) *Recorder {
+	return &Recorder{
+		log:     &logger.Logger{Logger: log.With(slog.String("context", "analytics"))},
+		saver:   saver,
+		cfg:     cfg,
+		trusted: cfg.TrustedPrefixes(),
+		events:  make(chan storage.Click, cfg.QueueSize),
+	}
+}
+
+func (r *Recorder) Start() {
+	for i := 0; i < r.cfg.Workers; i++ {
+		r.wg.Add(1)
+		go r.run()
+	}
+}
+
+// Stop stops accepting clicks and waits until everything already queued has
+// been saved.
+func (r *Recorder) Stop() {
+	r.mu.Lock()
+	if !r.stopped {
+		r.stopped = true
+		close(r.events)
+	}
+	r.mu.Unlock()
+
+	r.wg.Wait()
+}
+
+func (r *Recorder) Record(alias string, req *http.Request) {
+	click := storage.Click{
+		Alias:     alias,
+		At:        time.Now(),
+		Referrer:  req.Referer(),
+		UserAgent: req.UserAgent(),
+		IPHash:    security.HashIP(clientIP(req, r.trusted), r.cfg.IPSalt),
+		RequestID: middleware.GetReqID(req.Context()),
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.stopped {
+		r.dropped.Add(1)
+		return
+	}
+
+	if r.cfg.Overflow == config.OverflowBlock {
+		r.events <- click
+		return
+	}
+
+	select {
+	case r.events <- click:
+	default:
+		r.dropped.Add(1)
+	}
+}
+
+// QueueDepth reports how many clicks are waiting to be saved.
+func (r *Recorder) QueueDepth() int {
+	return len(r.events)
+}
+
+// Dropped reports how many clicks were discarded because the queue was full
+// or the recorder was stopped.
+func (r *Recorder) Dropped() int64 {
+	return r.dropped.Load()
+}
+
+func (r *Recorder) run() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]storage.Click, 0, r.cfg.BatchSize)
+
+	for {
+		select {
+		case click, ok := <-r.events:
+			if !ok {
+				r.flush(batch)
+				return
+			}
+
+			batch = append(batch, click)
+			if len(batch) >= r.cfg.BatchSize {
+				r.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			r.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (r *Recorder) flush(batch []storage.Click) {
+	if len(batch) == 0 {
+		return
+	}
+
+	if err := r.saver.SaveClicks(batch); err != nil {
+		r.log.Error("failed to save clicks",
+			slog.Int("count", len(batch)),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// clientIP returns the visitor address. On connections from a trusted proxy
+// it is the rightmost X-Forwarded-For address that is not a trusted proxy
+// itself: the addresses left of it are set by the client and can be anything.
+// Anyone else could forge the header, so it is ignored for them.
+func clientIP(r *http.Request, trusted []netip.Prefix) string {
+	ip := remoteIP(r)
+	if !isTrusted(ip, trusted) {
+		return ip
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		if !isTrusted(hop, trusted) {
+			return hop
+		}
+		ip = hop
+	}
+
+	return ip
+}
+
+func isTrusted(ip string, trusted []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
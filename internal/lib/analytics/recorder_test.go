@@ -0,0 +1,205 @@
+package analytics
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+	"url-shortener/internal/config"
+	"url-shortener/internal/lib/logger/handlers"
+	"url-shortener/internal/storage"
+)
+
+type fakeSaver struct {
+	mu      sync.Mutex
+	batches [][]storage.Click
+}
+
+func (s *fakeSaver) SaveClicks(clicks []storage.Click) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.batches = append(s.batches, append([]storage.Click(nil), clicks...))
+
+	return nil
+}
+
+func (s *fakeSaver) batchSizes() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sizes := make([]int, 0, len(s.batches))
+	for _, b := range s.batches {
+		sizes = append(sizes, len(b))
+	}
+
+	return sizes
+}
+
+func (s *fakeSaver) saved() int {
+	total := 0
+	for _, n := range s.batchSizes() {
+		total += n
+	}
+
+	return total
+}
+
+func newRecorder(saver ClickSaver, cfg config.Analytics) *Recorder {
+	if cfg.FlushInterval == 0 {
+		cfg.FlushInterval = time.Hour
+	}
+	if cfg.Workers == 0 {
+		cfg.Workers = 1
+	}
+	if cfg.Overflow == "" {
+		cfg.Overflow = config.OverflowDrop
+	}
+
+	return New(handlers.NewDiscardLogger(), saver, cfg)
+}
+
+func record(r *Recorder, n int) {
+	for i := 0; i < n; i++ {
+		r.Record("alias", httptest.NewRequest(http.MethodGet, "/alias", nil))
+	}
+}
+
+func TestRecorder_BatchesBySize(t *testing.T) {
+	t.Parallel()
+
+	saver := &fakeSaver{}
+	r := newRecorder(saver, config.Analytics{QueueSize: 10, BatchSize: 3})
+
+	record(r, 7)
+	r.Start()
+	r.Stop()
+
+	require.Equal(t, []int{3, 3, 1}, saver.batchSizes())
+}
+
+func TestRecorder_FlushesOnInterval(t *testing.T) {
+	t.Parallel()
+
+	saver := &fakeSaver{}
+	r := newRecorder(saver, config.Analytics{QueueSize: 10, BatchSize: 100, FlushInterval: 10 * time.Millisecond})
+	r.Start()
+	defer r.Stop()
+
+	record(r, 2)
+
+	require.Eventually(t, func() bool {
+		return saver.saved() == 2
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestRecorder_DropsWhenFull(t *testing.T) {
+	t.Parallel()
+
+	saver := &fakeSaver{}
+	r := newRecorder(saver, config.Analytics{QueueSize: 2, BatchSize: 10})
+
+	record(r, 5)
+	require.Equal(t, 2, r.QueueDepth())
+	require.Equal(t, int64(3), r.Dropped())
+
+	r.Start()
+	r.Stop()
+
+	require.Equal(t, 2, saver.saved())
+	require.Zero(t, r.QueueDepth())
+
+	record(r, 1)
+	require.Equal(t, int64(4), r.Dropped())
+}
+
+func TestRecorder_BlocksWhenFull(t *testing.T) {
+	t.Parallel()
+
+	saver := &fakeSaver{}
+	r := newRecorder(saver, config.Analytics{QueueSize: 1, BatchSize: 10, Overflow: config.OverflowBlock})
+
+	record(r, 1)
+
+	done := make(chan struct{})
+	go func() {
+		record(r, 1)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("record returned while the queue was full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	r.Start()
+	<-done
+	r.Stop()
+
+	require.Equal(t, 2, saver.saved())
+	require.Zero(t, r.Dropped())
+}
+
+func TestClientIP(t *testing.T) {
+	trusted := config.Analytics{TrustedProxies: []string{"10.0.0.0/8", "192.0.2.1"}}.TrustedPrefixes()
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		want         string
+	}{
+		{
+			name:       "Direct",
+			remoteAddr: "203.0.113.7:1234",
+			want:       "203.0.113.7",
+		},
+		{
+			name:         "Forged header",
+			remoteAddr:   "203.0.113.7:1234",
+			forwardedFor: []string{"198.51.100.1"},
+			want:         "203.0.113.7",
+		},
+		{
+			name:         "Trusted proxy",
+			remoteAddr:   "10.1.2.3:1234",
+			forwardedFor: []string{"198.51.100.1"},
+			want:         "198.51.100.1",
+		},
+		{
+			name:         "Proxy chain",
+			remoteAddr:   "10.1.2.3:1234",
+			forwardedFor: []string{"6.6.6.6, 198.51.100.1", "192.0.2.1"},
+			want:         "198.51.100.1",
+		},
+		{
+			name:         "Only proxies",
+			remoteAddr:   "10.1.2.3:1234",
+			forwardedFor: []string{"10.4.5.6"},
+			want:         "10.4.5.6",
+		},
+		{
+			name:       "Trusted proxy without header",
+			remoteAddr: "[::ffff:192.0.2.1]:1234",
+			want:       "::ffff:192.0.2.1",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/alias", nil)
+			req.RemoteAddr = tc.remoteAddr
+			for _, v := range tc.forwardedFor {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+
+			require.Equal(t, tc.want, clientIP(req, trusted))
+		})
+	}
+}
@@ -0,0 +1,277 @@
+// Package linkfile reads and writes links as CSV or JSON Lines, the formats
+// of the export and import endpoints.
+package linkfile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown format")
+	// ErrInvalidRecord marks a record that could not be parsed; reading can
+	// go on with the next one.
+	ErrInvalidRecord = errors.New("invalid record")
+)
+
+// maxLineSize bounds a JSON Lines record.
+const maxLineSize = 1 << 20
+
+type Record struct {
+	Alias     string     `json:"alias,omitempty"`
+	URL       string     `json:"url" validate:"required,url"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Clicks    int64      `json:"clicks" validate:"gte=0"`
+	MaxClicks int64      `json:"max_clicks,omitempty" validate:"gte=0"`
+	// Tags must not contain commas, which separate them in CSV.
+	Tags []string `json:"tags,omitempty" validate:"max=10,dive,required,max=32,excludesall=0x2C"`
+	// RedirectType is 0 for links that use the configured default.
+	RedirectType     int    `json:"redirect_type,omitempty" validate:"omitempty,oneof=301 302 307 308"`
+	QueryPassthrough string `json:"query_passthrough,omitempty" validate:"omitempty,oneof=keep override append"`
+	PathPassthrough  bool   `json:"path_passthrough,omitempty"`
+}
+
+// columns is the CSV header. Readers accept the columns in any order and
+// only require url.
+var columns = []string{
+	"alias", "url", "created_at", "expires_at", "clicks", "max_clicks", "tags",
+	"redirect_type", "query_passthrough", "path_passthrough",
+}
+
+type Writer interface {
+	Write(rec Record) error
+	// Flush writes out buffered records.
+	Flush() error
+}
+
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(columns); err != nil {
+			return nil, err
+		}
+		return csvWriter{w: cw}, nil
+	case FormatJSONL:
+		bw := bufio.NewWriter(w)
+		return jsonlWriter{w: bw, enc: json.NewEncoder(bw)}, nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (w csvWriter) Write(rec Record) error {
+	return w.w.Write([]string{
+		rec.Alias,
+		rec.URL,
+		formatTime(rec.CreatedAt),
+		formatTime(rec.ExpiresAt),
+		strconv.FormatInt(rec.Clicks, 10),
+		strconv.FormatInt(rec.MaxClicks, 10),
+		strings.Join(rec.Tags, ","),
+		formatOptionalInt(int64(rec.RedirectType)),
+		rec.QueryPassthrough,
+		formatOptionalBool(rec.PathPassthrough),
+	})
+}
+
+func (w csvWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type jsonlWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func (w jsonlWriter) Write(rec Record) error {
+	return w.enc.Encode(rec)
+}
+
+func (w jsonlWriter) Flush() error {
+	return w.w.Flush()
+}
+
+// Reader returns io.EOF after the last record.
+type Reader interface {
+	Read() (Record, error)
+}
+
+func NewReader(r io.Reader, format string) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r)
+	case FormatJSONL:
+		s := bufio.NewScanner(r)
+		s.Buffer(nil, maxLineSize)
+		return jsonlReader{s: s}, nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+type csvReader struct {
+	r      *csv.Reader
+	column map[string]int
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	column := make(map[string]int, len(header))
+	for i, name := range header {
+		column[strings.TrimSpace(name)] = i
+	}
+	if _, ok := column["url"]; !ok {
+		return nil, errors.New("header has no url column")
+	}
+
+	return &csvReader{r: cr, column: column}, nil
+}
+
+func (r *csvReader) Read() (Record, error) {
+	fields, err := r.r.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return Record{}, fmt.Errorf("%w: %v", ErrInvalidRecord, err)
+		}
+		return Record{}, err
+	}
+
+	field := func(name string) string {
+		if i, ok := r.column[name]; ok && i < len(fields) {
+			return strings.TrimSpace(fields[i])
+		}
+		return ""
+	}
+
+	rec := Record{
+		Alias: field("alias"),
+		URL:   field("url"),
+	}
+
+	if rec.CreatedAt, err = parseTime(field("created_at")); err != nil {
+		return Record{}, fmt.Errorf("%w: created_at: %v", ErrInvalidRecord, err)
+	}
+	if rec.ExpiresAt, err = parseTime(field("expires_at")); err != nil {
+		return Record{}, fmt.Errorf("%w: expires_at: %v", ErrInvalidRecord, err)
+	}
+	if rec.Clicks, err = parseInt(field("clicks")); err != nil {
+		return Record{}, fmt.Errorf("%w: clicks: %v", ErrInvalidRecord, err)
+	}
+	if rec.MaxClicks, err = parseInt(field("max_clicks")); err != nil {
+		return Record{}, fmt.Errorf("%w: max_clicks: %v", ErrInvalidRecord, err)
+	}
+	redirectType, err := parseInt(field("redirect_type"))
+	if err != nil {
+		return Record{}, fmt.Errorf("%w: redirect_type: %v", ErrInvalidRecord, err)
+	}
+	rec.RedirectType = int(redirectType)
+	rec.QueryPassthrough = field("query_passthrough")
+	if rec.PathPassthrough, err = parseBool(field("path_passthrough")); err != nil {
+		return Record{}, fmt.Errorf("%w: path_passthrough: %v", ErrInvalidRecord, err)
+	}
+	if tags := field("tags"); tags != "" {
+		rec.Tags = strings.Split(tags, ",")
+	}
+
+	return rec, nil
+}
+
+type jsonlReader struct {
+	s *bufio.Scanner
+}
+
+func (r jsonlReader) Read() (Record, error) {
+	for r.s.Scan() {
+		line := r.s.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var rec Record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return Record{}, fmt.Errorf("%w: %v", ErrInvalidRecord, err)
+		}
+		return rec, nil
+	}
+
+	if err := r.s.Err(); err != nil {
+		return Record{}, err
+	}
+	return Record{}, io.EOF
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func parseTime(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// formatOptionalInt leaves out zero, which stands for a default.
+func formatOptionalInt(n int64) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+func parseInt(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+func formatOptionalBool(b bool) string {
+	if !b {
+		return ""
+	}
+	return "true"
+}
+
+func parseBool(s string) (bool, error) {
+	if s == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(s)
+}
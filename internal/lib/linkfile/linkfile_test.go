@@ -0,0 +1,122 @@
+package linkfile
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRoundTrip(t *testing.T) {
+	createdAt := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	records := []Record{
+		{
+			Alias:     "full",
+			URL:       "https://example.com/?a=1,b=2",
+			CreatedAt: &createdAt,
+			ExpiresAt: &createdAt,
+			Clicks:    7,
+			MaxClicks: 10,
+			Tags:      []string{"docs", "work"},
+
+			RedirectType:     301,
+			QueryPassthrough: "append",
+			PathPassthrough:  true,
+		},
+		{
+			URL: "https://example.com/bare",
+		},
+	}
+
+	for _, format := range []string{FormatCSV, FormatJSONL} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			w, err := NewWriter(&buf, format)
+			require.NoError(t, err)
+			for _, rec := range records {
+				require.NoError(t, w.Write(rec))
+			}
+			require.NoError(t, w.Flush())
+
+			r, err := NewReader(&buf, format)
+			require.NoError(t, err)
+
+			var got []Record
+			for {
+				rec, err := r.Read()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				got = append(got, rec)
+			}
+
+			require.Equal(t, records, got)
+		})
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	input := strings.Join([]string{
+		"url,alias",
+		"https://example.com/1,one",
+		"https://example.com/2",
+		`"https://example.com/3`,
+	}, "\n")
+
+	r, err := NewReader(strings.NewReader(input), FormatCSV)
+	require.NoError(t, err)
+
+	rec, err := r.Read()
+	require.NoError(t, err)
+	require.Equal(t, Record{Alias: "one", URL: "https://example.com/1"}, rec)
+
+	// Missing trailing columns are empty.
+	rec, err = r.Read()
+	require.NoError(t, err)
+	require.Equal(t, Record{URL: "https://example.com/2"}, rec)
+
+	_, err = r.Read()
+	require.ErrorIs(t, err, ErrInvalidRecord)
+
+	_, err = NewReader(strings.NewReader("alias\none"), FormatCSV)
+	require.Error(t, err)
+}
+
+func TestJSONLReader(t *testing.T) {
+	input := `{"url": "https://example.com/1", "clicks": 3}
+
+{"url": 
+{"url": "https://example.com/2"}
+`
+
+	r, err := NewReader(strings.NewReader(input), FormatJSONL)
+	require.NoError(t, err)
+
+	rec, err := r.Read()
+	require.NoError(t, err)
+	require.Equal(t, Record{URL: "https://example.com/1", Clicks: 3}, rec)
+
+	// A broken line does not stop the reader.
+	_, err = r.Read()
+	require.ErrorIs(t, err, ErrInvalidRecord)
+
+	rec, err = r.Read()
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/2", rec.URL)
+
+	_, err = r.Read()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestUnknownFormat(t *testing.T) {
+	_, err := NewWriter(io.Discard, "xml")
+	require.ErrorIs(t, err, ErrUnknownFormat)
+
+	_, err = NewReader(strings.NewReader(""), "xml")
+	require.ErrorIs(t, err, ErrUnknownFormat)
+}
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+)
+
+const namespace = "url_shortener"
+
+// Metrics holds every collector the service exports. Each instance has its
+// own registry, so tests can create as many as they like.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	HTTPRequests        *prometheus.CounterVec
+	HTTPRequestDuration *prometheus.HistogramVec
+	StorageDuration     *prometheus.HistogramVec
+
+	redirects         prometheus.Counter
+	redirectsNotFound prometheus.Counter
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		HTTPRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		HTTPRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method and route pattern.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		StorageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "storage_operation_duration_seconds",
+			Help:      "Storage call latency by method.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"method"}),
+		redirects: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "redirects_total",
+			Help:      "Redirects served.",
+		}),
+		redirectsNotFound: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "redirects_not_found_total",
+			Help:      "Redirect requests for unknown aliases.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.HTTPRequests,
+		m.HTTPRequestDuration,
+		m.StorageDuration,
+		m.redirects,
+		m.redirectsNotFound,
+	)
+
+	return m
+}
+
+func (m *Metrics) Redirected() {
+	m.redirects.Inc()
+}
+
+func (m *Metrics) NotFound() {
+	m.redirectsNotFound.Inc()
+}
+
+type ClickQueue interface {
+	QueueDepth() int
+	Dropped() int64
+}
+
+// RegisterClickQueue exports the depth and drop count of the click queue.
+func (m *Metrics) RegisterClickQueue(q ClickQueue) {
+	m.registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "click_queue_depth",
+			Help:      "Clicks waiting to be saved.",
+		}, func() float64 {
+			return float64(q.QueueDepth())
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "click_queue_dropped_total",
+			Help:      "Clicks dropped because the queue was full or stopped.",
+		}, func() float64 {
+			return float64(q.Dropped())
+		}),
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
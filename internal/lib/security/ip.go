@@ -0,0 +1,16 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// HashIP returns a keyed digest of a visitor address. It lets us count unique
+// visitors without storing addresses; without the key the digest cannot be
+// reversed by enumerating the address space.
+func HashIP(ip string, key string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil))
+}
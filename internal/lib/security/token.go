@@ -1,11 +1,27 @@
 package security
 
 import (
-	"url-shortener/internal/lib/random"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 )
 
-const tokenLength = 16
+// 256 bits of entropy.
+const tokenBytes = 32
 
-func GenerateToken() string {
-	return random.String(tokenLength)
+func GenerateToken() (string, error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the hex-encoded SHA-256 digest under which a session
+// token is stored.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,70 @@
+package sweeper
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+	"url-shortener/internal/lib/logger"
+)
+
+// SweepFunc removes stale rows and reports how many were removed.
+type SweepFunc func() (int64, error)
+
+// Sweeper runs a SweepFunc on a fixed interval in a background goroutine.
+type Sweeper struct {
+	log      *logger.Logger
+	interval time.Duration
+	sweep    SweepFunc
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func New(log *logger.Logger, name string, interval time.Duration, sweep SweepFunc) *Sweeper {
+	return &Sweeper{
+		log: &logger.Logger{Logger: log.With(
+			slog.String("context", "sweeper"),
+			slog.String("name", name),
+		)},
+		interval: interval,
+		sweep:    sweep,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func (s *Sweeper) Start() {
+	go s.run()
+}
+
+// Stop signals the sweeper to exit and waits for a running sweep to finish.
+func (s *Sweeper) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+	<-s.done
+}
+
+func (s *Sweeper) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			removed, err := s.sweep()
+			if err != nil {
+				s.log.Error("sweep failed", slog.String("error", err.Error()))
+				continue
+			}
+			if removed > 0 {
+				s.log.Info("sweep completed", slog.Int64("removed", removed))
+			}
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Package validation validates request structs with a single, shared
+// validator and reports failures per field.
+package validation
+
+import (
+	"errors"
+	"github.com/go-playground/locales/en"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	enTranslations "github.com/go-playground/validator/v10/translations/en"
+	"reflect"
+	"strings"
+)
+
+// FieldError describes one failed rule. Field is the JSON name of the field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return e.Message
+}
+
+// validate and trans are safe for concurrent use and expensive to build, so
+// they are built once.
+var validate, trans = newValidator()
+
+func newValidator() (*validator.Validate, ut.Translator) {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// Report fields by the name clients send them under.
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+
+	locale := en.New()
+	trans, _ := ut.New(locale, locale).GetTranslator("en")
+	if err := enTranslations.RegisterDefaultTranslations(v, trans); err != nil {
+		panic(err)
+	}
+
+	return v, trans
+}
+
+// Struct validates s and returns one FieldError per failed rule, or nil when
+// s is valid.
+func Struct(s any) []FieldError {
+	err := validate.Struct(s)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		// Only happens for arguments that are not structs: a programming error.
+		panic(err)
+	}
+
+	fieldErrs := make([]FieldError, 0, len(validationErrs))
+	for _, fe := range validationErrs {
+		fieldErrs = append(fieldErrs, FieldError{
+			Field:   fe.Field(),
+			Rule:    fe.Tag(),
+			Message: fe.Translate(trans),
+		})
+	}
+
+	return fieldErrs
+}
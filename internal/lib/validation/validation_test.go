@@ -0,0 +1,28 @@
+package validation
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type request struct {
+	URL      string `json:"url" validate:"required,url"`
+	Alias    string `json:"alias,omitempty" validate:"omitempty,alphanum"`
+	Quantity int64  `json:"quantity" validate:"gte=0"`
+}
+
+func TestStruct(t *testing.T) {
+	require.Nil(t, Struct(request{URL: "https://example.com"}))
+
+	errs := Struct(request{Alias: "not valid", Quantity: -1})
+	require.Equal(t, []FieldError{
+		{Field: "url", Rule: "required", Message: "url is a required field"},
+		{Field: "alias", Rule: "alphanum", Message: "alias can only contain alphanumeric characters"},
+		{Field: "quantity", Rule: "gte", Message: "quantity must be 0 or greater"},
+	}, errs)
+
+	errs = Struct(request{URL: "not a url"})
+	require.Equal(t, []FieldError{
+		{Field: "url", Rule: "url", Message: "url must be a valid URL"},
+	}, errs)
+}
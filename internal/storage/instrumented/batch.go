@@ -0,0 +1,31 @@
+package instrumented
+
+import (
+	"time"
+	"url-shortener/internal/storage"
+)
+
+type batch struct {
+	batch storage.URLBatch
+	s     *Storage
+}
+
+func (b *batch) NextURLID() (int64, error) {
+	defer b.s.observe("URLBatch.NextURLID", time.Now())
+	return b.batch.NextURLID()
+}
+
+func (b *batch) SaveURL(urlToSave string, alias string, userId int64, opts storage.URLOptions) (int64, error) {
+	defer b.s.observe("URLBatch.SaveURL", time.Now())
+	return b.batch.SaveURL(urlToSave, alias, userId, opts)
+}
+
+func (b *batch) Commit() error {
+	defer b.s.observe("URLBatch.Commit", time.Now())
+	return b.batch.Commit()
+}
+
+func (b *batch) Rollback() error {
+	defer b.s.observe("URLBatch.Rollback", time.Now())
+	return b.batch.Rollback()
+}
@@ -0,0 +1,155 @@
+// Package instrumented wraps a storage.Store and records the latency of every
+// call, labelled with the method name.
+package instrumented
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
+	"url-shortener/internal/storage"
+)
+
+var _ storage.Store = (*Storage)(nil)
+
+type Storage struct {
+	store    storage.Store
+	duration *prometheus.HistogramVec
+}
+
+func New(store storage.Store, duration *prometheus.HistogramVec) *Storage {
+	return &Storage{
+		store:    store,
+		duration: duration,
+	}
+}
+
+func (s *Storage) observe(method string, start time.Time) {
+	s.duration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
+
+func (s *Storage) NextURLID() (int64, error) {
+	defer s.observe("NextURLID", time.Now())
+	return s.store.NextURLID()
+}
+
+func (s *Storage) SaveURL(urlToSave string, alias string, userId int64, opts storage.URLOptions) (int64, error) {
+	defer s.observe("SaveURL", time.Now())
+	return s.store.SaveURL(urlToSave, alias, userId, opts)
+}
+
+func (s *Storage) BeginURLBatch() (storage.URLBatch, error) {
+	defer s.observe("BeginURLBatch", time.Now())
+	b, err := s.store.BeginURLBatch()
+	if err != nil {
+		return nil, err
+	}
+	return &batch{batch: b, s: s}, nil
+}
+
+func (s *Storage) GetURL(alias string) (string, error) {
+	defer s.observe("GetURL", time.Now())
+	return s.store.GetURL(alias)
+}
+
+func (s *Storage) HitURL(alias string, subPath bool) (storage.URLTarget, error) {
+	defer s.observe("HitURL", time.Now())
+	return s.store.HitURL(alias, subPath)
+}
+
+func (s *Storage) UpdateURL(alias string, userId int64, upd storage.URLUpdate) error {
+	defer s.observe("UpdateURL", time.Now())
+	return s.store.UpdateURL(alias, userId, upd)
+}
+
+func (s *Storage) GetURLHistory(alias string, userId int64) ([]storage.URLChange, error) {
+	defer s.observe("GetURLHistory", time.Now())
+	return s.store.GetURLHistory(alias, userId)
+}
+
+func (s *Storage) ListURLs(userId int64, q storage.URLQuery) ([]storage.URLInfo, error) {
+	defer s.observe("ListURLs", time.Now())
+	return s.store.ListURLs(userId, q)
+}
+
+func (s *Storage) WalkURLs(userId int64, visit func(storage.URLInfo) error) error {
+	defer s.observe("WalkURLs", time.Now())
+	return s.store.WalkURLs(userId, visit)
+}
+
+func (s *Storage) DeleteURL(alias string, userId int64) error {
+	defer s.observe("DeleteURL", time.Now())
+	return s.store.DeleteURL(alias, userId)
+}
+
+func (s *Storage) ReplaceURL(urlToSave string, alias string, userId int64, opts storage.URLOptions) (int64, error) {
+	defer s.observe("ReplaceURL", time.Now())
+	return s.store.ReplaceURL(urlToSave, alias, userId, opts)
+}
+
+func (s *Storage) DeleteExpiredURLs(before time.Time) (int64, error) {
+	defer s.observe("DeleteExpiredURLs", time.Now())
+	return s.store.DeleteExpiredURLs(before)
+}
+
+func (s *Storage) CreateUser(username string, password string) (int64, error) {
+	defer s.observe("CreateUser", time.Now())
+	return s.store.CreateUser(username, password)
+}
+
+func (s *Storage) AuthenticateUser(username string, password string) (int64, error) {
+	defer s.observe("AuthenticateUser", time.Now())
+	return s.store.AuthenticateUser(username, password)
+}
+
+func (s *Storage) CreateSession(userId int64, token string, expiresAt time.Time) (int64, error) {
+	defer s.observe("CreateSession", time.Now())
+	return s.store.CreateSession(userId, token, expiresAt)
+}
+
+func (s *Storage) GetSessionUserID(token string) (int64, error) {
+	defer s.observe("GetSessionUserID", time.Now())
+	return s.store.GetSessionUserID(token)
+}
+
+func (s *Storage) ExtendSession(token string, expiresAt time.Time) error {
+	defer s.observe("ExtendSession", time.Now())
+	return s.store.ExtendSession(token, expiresAt)
+}
+
+func (s *Storage) DeleteSession(token string) error {
+	defer s.observe("DeleteSession", time.Now())
+	return s.store.DeleteSession(token)
+}
+
+func (s *Storage) DeleteUserSessions(userId int64) error {
+	defer s.observe("DeleteUserSessions", time.Now())
+	return s.store.DeleteUserSessions(userId)
+}
+
+func (s *Storage) DeleteExpiredSessions() (int64, error) {
+	defer s.observe("DeleteExpiredSessions", time.Now())
+	return s.store.DeleteExpiredSessions()
+}
+
+func (s *Storage) SaveClicks(clicks []storage.Click) error {
+	defer s.observe("SaveClicks", time.Now())
+	return s.store.SaveClicks(clicks)
+}
+
+func (s *Storage) GetURLStats(alias string, userId int64) (storage.URLStats, error) {
+	defer s.observe("GetURLStats", time.Now())
+	return s.store.GetURLStats(alias, userId)
+}
+
+func (s *Storage) Ping() error {
+	defer s.observe("Ping", time.Now())
+	return s.store.Ping()
+}
+
+func (s *Storage) SchemaVersion() (int64, error) {
+	defer s.observe("SchemaVersion", time.Now())
+	return s.store.SchemaVersion()
+}
+
+func (s *Storage) Close() error {
+	return s.store.Close()
+}
@@ -0,0 +1,41 @@
+package instrumented
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/memory"
+	"url-shortener/internal/storage/storagetest"
+)
+
+func newDuration() *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test"}, []string{"method"})
+}
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Store {
+		return New(memory.New(), newDuration())
+	})
+}
+
+func TestStorage_ObservesMethods(t *testing.T) {
+	duration := newDuration()
+	s := New(memory.New(), duration)
+
+	_, err := s.SaveURL("https://example.com", "alias", 1, storage.URLOptions{})
+	require.NoError(t, err)
+	_, err = s.GetURL("alias")
+	require.NoError(t, err)
+	_, err = s.GetURL("missing")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	// One series per method, with every call observed.
+	require.Equal(t, 2, testutil.CollectAndCount(duration))
+
+	var m dto.Metric
+	require.NoError(t, duration.WithLabelValues("GetURL").(prometheus.Histogram).Write(&m))
+	require.Equal(t, uint64(2), m.GetHistogram().GetSampleCount())
+}
@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"fmt"
+	"url-shortener/internal/storage"
+)
+
+// batch holds the store lock until it ends, which keeps the links it saved
+// invisible to everyone else until Commit.
+type batch struct {
+	s       *Storage
+	aliases []string
+	done    bool
+}
+
+func (s *Storage) BeginURLBatch() (storage.URLBatch, error) {
+	s.mu.Lock()
+
+	return &batch{s: s}, nil
+}
+
+func (b *batch) NextURLID() (int64, error) {
+	b.s.lastURLId++
+
+	return b.s.lastURLId, nil
+}
+
+func (b *batch) SaveURL(urlToSave string, alias string, userId int64, opts storage.URLOptions) (int64, error) {
+	const fn = "storage.memory.batch.SaveURL"
+
+	id, err := b.s.saveURL(urlToSave, alias, userId, opts)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	b.aliases = append(b.aliases, alias)
+
+	return id, nil
+}
+
+func (b *batch) Commit() error {
+	if !b.done {
+		b.done = true
+		b.s.mu.Unlock()
+	}
+
+	return nil
+}
+
+func (b *batch) Rollback() error {
+	if !b.done {
+		for _, alias := range b.aliases {
+			b.s.deleteURL(alias)
+		}
+
+		b.done = true
+		b.s.mu.Unlock()
+	}
+
+	return nil
+}
@@ -0,0 +1,608 @@
+package memory
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"url-shortener/internal/lib/security"
+	"url-shortener/internal/storage"
+)
+
+var _ storage.Store = (*Storage)(nil)
+
+type url struct {
+	id           int64
+	url          string
+	userId       int64
+	expiresAt    time.Time
+	clicks       int64
+	maxClicks    int64
+	createdAt    time.Time
+	redirectType int
+	passthrough  storage.Passthrough
+	tags         []string
+	visits       []storage.Click
+	history      []storage.URLChange
+}
+
+func (u url) expired(now time.Time) bool {
+	return !u.expiresAt.IsZero() && !u.expiresAt.After(now)
+}
+
+type user struct {
+	id       int64
+	password string
+}
+
+type session struct {
+	id        int64
+	userId    int64
+	expiresAt time.Time
+}
+
+// Storage keeps everything in process memory. It is safe for concurrent use
+// and loses all data on restart.
+type Storage struct {
+	mu sync.RWMutex
+
+	urls map[string]url
+	// userURLs indexes the aliases of every user's links, so that listings
+	// do not look at the links of others.
+	userURLs map[int64]map[string]struct{}
+	users    map[string]user
+	sessions map[string]session
+
+	lastURLId     int64
+	lastUserId    int64
+	lastSessionId int64
+}
+
+func New() *Storage {
+	return &Storage{
+		urls:     make(map[string]url),
+		userURLs: make(map[int64]map[string]struct{}),
+		users:    make(map[string]user),
+		sessions: make(map[string]session),
+	}
+}
+
+func (s *Storage) Ping() error {
+	return nil
+}
+
+func (s *Storage) SchemaVersion() (int64, error) {
+	return 0, nil
+}
+
+func (s *Storage) Close() error {
+	return nil
+}
+
+func (s *Storage) NextURLID() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastURLId++
+
+	return s.lastURLId, nil
+}
+
+func (s *Storage) SaveURL(urlToSave string, alias string, userId int64, opts storage.URLOptions) (int64, error) {
+	const fn = "storage.memory.SaveURL"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, err := s.saveURL(urlToSave, alias, userId, opts)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return id, nil
+}
+
+// saveURL is SaveURL for callers holding the lock.
+func (s *Storage) saveURL(urlToSave string, alias string, userId int64, opts storage.URLOptions) (int64, error) {
+	if _, ok := s.urls[alias]; ok {
+		return 0, storage.ErrURLExists
+	}
+
+	id := opts.ID
+	if id == 0 {
+		s.lastURLId++
+		id = s.lastURLId
+	}
+
+	u := url{
+		id:           id,
+		url:          urlToSave,
+		userId:       userId,
+		expiresAt:    opts.ExpiresAt,
+		maxClicks:    opts.MaxClicks,
+		clicks:       opts.Clicks,
+		createdAt:    opts.CreatedAt,
+		tags:         uniqueTags(opts.Tags),
+		redirectType: opts.RedirectType,
+		passthrough:  opts.Passthrough,
+	}
+	if u.createdAt.IsZero() {
+		u.createdAt = time.Now()
+	}
+
+	s.urls[alias] = u
+
+	aliases, ok := s.userURLs[userId]
+	if !ok {
+		aliases = make(map[string]struct{})
+		s.userURLs[userId] = aliases
+	}
+	aliases[alias] = struct{}{}
+
+	return id, nil
+}
+
+// deleteURL removes a link for callers holding the lock.
+func (s *Storage) deleteURL(alias string) {
+	u, ok := s.urls[alias]
+	if !ok {
+		return
+	}
+
+	delete(s.urls, alias)
+
+	delete(s.userURLs[u.userId], alias)
+	if len(s.userURLs[u.userId]) == 0 {
+		delete(s.userURLs, u.userId)
+	}
+}
+
+// uniqueTags returns tags sorted and without duplicates, as the SQL stores
+// keep them.
+func uniqueTags(tags []string) []string {
+	res := slices.Clone(tags)
+	sort.Strings(res)
+
+	return slices.Compact(res)
+}
+
+func (s *Storage) GetURL(alias string) (string, error) {
+	const fn = "storage.memory.GetURL"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.urls[alias]
+	if !ok {
+		return "", fmt.Errorf("%s: %w", fn, storage.ErrURLNotFound)
+	}
+	if u.expired(time.Now()) {
+		return "", fmt.Errorf("%s: %w", fn, storage.ErrURLExpired)
+	}
+
+	return u.url, nil
+}
+
+func (s *Storage) HitURL(alias string, subPath bool) (storage.URLTarget, error) {
+	const fn = "storage.memory.HitURL"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.urls[alias]
+	if !ok || subPath && !u.passthrough.PathPassthrough {
+		return storage.URLTarget{}, fmt.Errorf("%s: %w", fn, storage.ErrURLNotFound)
+	}
+	if u.expired(time.Now()) {
+		return storage.URLTarget{}, fmt.Errorf("%s: %w", fn, storage.ErrURLExpired)
+	}
+	if u.maxClicks > 0 && u.clicks >= u.maxClicks {
+		return storage.URLTarget{}, fmt.Errorf("%s: %w", fn, storage.ErrURLExhausted)
+	}
+
+	u.clicks++
+	s.urls[alias] = u
+
+	return storage.URLTarget{
+		URL:          u.url,
+		RedirectType: u.redirectType,
+		Passthrough:  u.passthrough,
+		ExpiresAt:    u.expiresAt,
+		MaxClicks:    u.maxClicks,
+	}, nil
+}
+
+func (s *Storage) UpdateURL(alias string, userId int64, upd storage.URLUpdate) error {
+	const fn = "storage.memory.UpdateURL"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.urls[alias]
+	if !ok {
+		return fmt.Errorf("%s: %w", fn, storage.ErrURLNotFound)
+	}
+	if u.userId != userId {
+		return fmt.Errorf("%s: %w", fn, storage.ErrForbidden)
+	}
+
+	if upd.URL != nil && *upd.URL != u.url {
+		u.history = append(u.history, storage.URLChange{
+			OldURL:    u.url,
+			NewURL:    *upd.URL,
+			ChangedAt: time.Now(),
+			ChangedBy: userId,
+		})
+		u.url = *upd.URL
+	}
+	if upd.ExpiresAt != nil {
+		u.expiresAt = *upd.ExpiresAt
+	}
+	if upd.RedirectType != nil {
+		u.redirectType = *upd.RedirectType
+	}
+	if upd.QueryPassthrough != nil {
+		u.passthrough.QueryPassthrough = *upd.QueryPassthrough
+	}
+	if upd.PathPassthrough != nil {
+		u.passthrough.PathPassthrough = *upd.PathPassthrough
+	}
+
+	s.urls[alias] = u
+
+	return nil
+}
+
+func (s *Storage) GetURLHistory(alias string, userId int64) ([]storage.URLChange, error) {
+	const fn = "storage.memory.GetURLHistory"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.urls[alias]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", fn, storage.ErrURLNotFound)
+	}
+	if u.userId != userId {
+		return nil, fmt.Errorf("%s: %w", fn, storage.ErrForbidden)
+	}
+
+	history := make([]storage.URLChange, 0, len(u.history))
+	for i := len(u.history) - 1; i >= 0; i-- {
+		history = append(history, u.history[i])
+	}
+
+	return history, nil
+}
+
+func (s *Storage) ListURLs(userId int64, q storage.URLQuery) ([]storage.URLInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	search := strings.ToLower(q.Search)
+
+	var urls []storage.URLInfo
+	for alias := range s.userURLs[userId] {
+		u := s.urls[alias]
+		if search != "" && !strings.Contains(strings.ToLower(alias), search) && !strings.Contains(strings.ToLower(u.url), search) {
+			continue
+		}
+		if q.Tag != "" && !slices.Contains(u.tags, q.Tag) {
+			continue
+		}
+
+		switch q.Expiry {
+		case storage.ExpiryExpired:
+			if !u.expired(now) {
+				continue
+			}
+		case storage.ExpiryExpiring:
+			if u.expiresAt.IsZero() || u.expired(now) {
+				continue
+			}
+		case storage.ExpiryPermanent:
+			if !u.expiresAt.IsZero() {
+				continue
+			}
+		}
+
+		urls = append(urls, storage.URLInfo{
+			ID:        u.id,
+			Alias:     alias,
+			URL:       u.url,
+			CreatedAt: u.createdAt,
+			ExpiresAt: u.expiresAt,
+			Clicks:    u.clicks,
+			MaxClicks: u.maxClicks,
+			Tags:      slices.Clone(u.tags),
+
+			RedirectType: u.redirectType,
+			Passthrough:  u.passthrough,
+		})
+	}
+
+	before := func(a, b storage.URLInfo) bool {
+		if q.Sort == storage.SortClicks && a.Clicks != b.Clicks {
+			return a.Clicks > b.Clicks
+		}
+		if q.Sort != storage.SortClicks && !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	}
+
+	sort.Slice(urls, func(i, j int) bool {
+		return before(urls[i], urls[j])
+	})
+
+	if q.After != nil {
+		after := storage.URLInfo{ID: q.After.ID, Clicks: q.After.Clicks, CreatedAt: q.After.CreatedAt}
+		urls = slices.DeleteFunc(urls, func(u storage.URLInfo) bool {
+			return !before(after, u)
+		})
+	}
+
+	return urls[:min(len(urls), q.Limit)], nil
+}
+
+func (s *Storage) WalkURLs(userId int64, visit func(storage.URLInfo) error) error {
+	// Walk a snapshot rather than holding the lock while visiting.
+	urls, err := s.ListURLs(userId, storage.URLQuery{Limit: math.MaxInt})
+	if err != nil {
+		return err
+	}
+
+	for i := len(urls) - 1; i >= 0; i-- {
+		if err := visit(urls[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteURL(alias string, userId int64) error {
+	const fn = "storage.memory.DeleteURL"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.urls[alias]
+	if !ok {
+		return fmt.Errorf("%s: %w", fn, storage.ErrURLNotFound)
+	}
+	if u.userId != userId {
+		return fmt.Errorf("%s: %w", fn, storage.ErrForbidden)
+	}
+
+	s.deleteURL(alias)
+
+	return nil
+}
+
+func (s *Storage) ReplaceURL(urlToSave string, alias string, userId int64, opts storage.URLOptions) (int64, error) {
+	const fn = "storage.memory.ReplaceURL"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.urls[alias]
+	if !ok {
+		return 0, fmt.Errorf("%s: %w", fn, storage.ErrURLNotFound)
+	}
+	if u.userId != userId {
+		return 0, fmt.Errorf("%s: %w", fn, storage.ErrForbidden)
+	}
+
+	s.deleteURL(alias)
+
+	id, err := s.saveURL(urlToSave, alias, userId, opts)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) DeleteExpiredURLs(before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for alias, u := range s.urls {
+		if u.expired(before) {
+			s.deleteURL(alias)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+func (s *Storage) SaveClicks(clicks []storage.Click) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range clicks {
+		u, ok := s.urls[c.Alias]
+		if !ok {
+			continue
+		}
+
+		u.visits = append(u.visits, c)
+		s.urls[c.Alias] = u
+	}
+
+	return nil
+}
+
+func (s *Storage) GetURLStats(alias string, userId int64) (storage.URLStats, error) {
+	const fn = "storage.memory.GetURLStats"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var stats storage.URLStats
+
+	u, ok := s.urls[alias]
+	if !ok {
+		return stats, fmt.Errorf("%s: %w", fn, storage.ErrURLNotFound)
+	}
+	if u.userId != userId {
+		return stats, fmt.Errorf("%s: %w", fn, storage.ErrForbidden)
+	}
+
+	visitors := make(map[string]struct{})
+	daily := make(map[time.Time]int64)
+	for _, c := range u.visits {
+		if c.IPHash != "" {
+			visitors[c.IPHash] = struct{}{}
+		}
+
+		at := c.At.UTC()
+		daily[time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)]++
+	}
+
+	stats.TotalClicks = int64(len(u.visits))
+	stats.UniqueVisitors = int64(len(visitors))
+	for date, clicks := range daily {
+		stats.Daily = append(stats.Daily, storage.DailyClicks{Date: date, Clicks: clicks})
+	}
+	sort.Slice(stats.Daily, func(i, j int) bool {
+		return stats.Daily[i].Date.Before(stats.Daily[j].Date)
+	})
+
+	return stats, nil
+}
+
+func (s *Storage) CreateUser(username string, password string) (int64, error) {
+	const fn = "storage.memory.CreateUser"
+
+	// Hash outside the lock: bcrypt is deliberately slow.
+	hashedPassword, err := security.HashPassword(password)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[username]; ok {
+		return 0, fmt.Errorf("%s: %w", fn, storage.ErrUserExists)
+	}
+
+	s.lastUserId++
+	s.users[username] = user{
+		id:       s.lastUserId,
+		password: hashedPassword,
+	}
+
+	return s.lastUserId, nil
+}
+
+func (s *Storage) AuthenticateUser(username string, password string) (int64, error) {
+	const fn = "storage.memory.AuthenticateUser"
+
+	s.mu.RLock()
+	u, ok := s.users[username]
+	s.mu.RUnlock()
+
+	if !ok {
+		return 0, fmt.Errorf("%s: %w", fn, storage.ErrUserNotFound)
+	}
+
+	auth := security.VerifyPassword(password, u.password)
+	if !auth {
+		return 0, nil
+	}
+
+	return u.id, nil
+}
+
+func (s *Storage) CreateSession(userId int64, token string, expiresAt time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastSessionId++
+	s.sessions[security.HashToken(token)] = session{
+		id:        s.lastSessionId,
+		userId:    userId,
+		expiresAt: expiresAt,
+	}
+
+	return s.lastSessionId, nil
+}
+
+func (s *Storage) GetSessionUserID(token string) (int64, error) {
+	const fn = "storage.memory.GetSessionUserID"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sess, ok := s.sessions[security.HashToken(token)]
+	if !ok || !sess.expiresAt.After(time.Now()) {
+		return 0, fmt.Errorf("%s: %w", fn, storage.ErrSessionNotFound)
+	}
+
+	return sess.userId, nil
+}
+
+func (s *Storage) ExtendSession(token string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokenHash := security.HashToken(token)
+
+	sess, ok := s.sessions[tokenHash]
+	if !ok {
+		return nil
+	}
+
+	sess.expiresAt = expiresAt
+	s.sessions[tokenHash] = sess
+
+	return nil
+}
+
+func (s *Storage) DeleteSession(token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, security.HashToken(token))
+
+	return nil
+}
+
+func (s *Storage) DeleteUserSessions(userId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for tokenHash, sess := range s.sessions {
+		if sess.userId == userId {
+			delete(s.sessions, tokenHash)
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteExpiredSessions() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	var deleted int64
+	for tokenHash, sess := range s.sessions {
+		if !sess.expiresAt.After(now) {
+			delete(s.sessions, tokenHash)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/storagetest"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Store {
+		return New()
+	})
+}
+
+func TestStorage_ConcurrentSaveURL(t *testing.T) {
+	s := New()
+
+	const workers = 50
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	saved := 0
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			_, err := s.SaveURL("https://example.com/"+strconv.Itoa(i), "alias", int64(i), storage.URLOptions{})
+			if err == nil {
+				mu.Lock()
+				saved++
+				mu.Unlock()
+				return
+			}
+			require.ErrorIs(t, err, storage.ErrURLExists)
+		}(i)
+	}
+
+	wg.Wait()
+
+	require.Equal(t, 1, saved)
+}
+
+func TestStorage_UserIndex(t *testing.T) {
+	s := New()
+
+	_, err := s.SaveURL("https://example.com/kept", "kept", 1, storage.URLOptions{})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://example.com/deleted", "deleted", 1, storage.URLOptions{})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://example.com/expired", "expired", 2, storage.URLOptions{ExpiresAt: time.Now()})
+	require.NoError(t, err)
+
+	b, err := s.BeginURLBatch()
+	require.NoError(t, err)
+	_, err = b.SaveURL("https://example.com/rolled-back", "rolled-back", 3, storage.URLOptions{})
+	require.NoError(t, err)
+	require.NoError(t, b.Rollback())
+
+	require.NoError(t, s.DeleteURL("deleted", 1))
+	_, err = s.DeleteExpiredURLs(time.Now())
+	require.NoError(t, err)
+
+	// Every way out of the store leaves the index too.
+	require.Equal(t, map[int64]map[string]struct{}{1: {"kept": {}}}, s.userURLs)
+}
@@ -0,0 +1,360 @@
+// Package migrator applies numbered SQL migrations and records them in a
+// schema_migrations table. Migrations are read from files named
+// <version>_<name>.up.sql and <version>_<name>.down.sql; each one runs in its
+// own transaction together with its version bookkeeping.
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+var ErrNoDownScript = errors.New("migration has no down script")
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type Option func(m *Migrator)
+
+// WithLock runs lock before and unlock after every Up and Down on the same
+// connection, so that concurrently starting replicas migrate one at a time.
+func WithLock(lock string, unlock string) Option {
+	return func(m *Migrator) {
+		m.lock = lock
+		m.unlock = unlock
+	}
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	lock       string
+	unlock     string
+}
+
+func New(db *sql.DB, fsys fs.FS, opts ...Option) (*Migrator, error) {
+	const fn = "storage.migrator.New"
+
+	migrations, err := load(fsys)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	m := &Migrator{
+		db:         db,
+		migrations: migrations,
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m, nil
+}
+
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file %q", entry.Name())
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration file %q: %w", entry.Name(), err)
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d has no up script", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Up applies every pending migration in order and returns the applied ones.
+func (m *Migrator) Up() ([]Migration, error) {
+	const fn = "storage.migrator.Up"
+
+	var applied []Migration
+
+	err := m.withConn(func(conn *sql.Conn) error {
+		versions, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := versions[migration.Version]; ok {
+				continue
+			}
+
+			err := inTx(conn, func(tx *sql.Tx) error {
+				if _, err := tx.Exec(migration.Up); err != nil {
+					return err
+				}
+
+				_, err := tx.Exec(
+					"INSERT INTO schema_migrations (version, applied_at) VALUES ($1, $2)",
+					migration.Version, time.Now().UTC(),
+				)
+
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			applied = append(applied, migration)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return applied, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return applied, nil
+}
+
+// Down reverts the latest applied migration and returns it, or nil when no
+// migration is applied.
+func (m *Migrator) Down() (*Migration, error) {
+	const fn = "storage.migrator.Down"
+
+	var reverted *Migration
+
+	err := m.withConn(func(conn *sql.Conn) error {
+		versions, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := versions[migration.Version]; !ok {
+				continue
+			}
+
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, ErrNoDownScript)
+			}
+
+			err := inTx(conn, func(tx *sql.Tx) error {
+				if _, err := tx.Exec(migration.Down); err != nil {
+					return err
+				}
+
+				_, err := tx.Exec("DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			reverted = &migration
+
+			return nil
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return reverted, nil
+}
+
+// Status lists every known migration and whether it has been applied.
+func (m *Migrator) Status() ([]Status, error) {
+	const fn = "storage.migrator.Status"
+
+	var statuses []Status
+
+	err := m.withConn(func(conn *sql.Conn) error {
+		versions, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			appliedAt, ok := versions[migration.Version]
+			statuses = append(statuses, Status{
+				Migration: migration,
+				Applied:   ok,
+				AppliedAt: appliedAt,
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return statuses, nil
+}
+
+// Version returns the highest applied migration version, 0 if none.
+func (m *Migrator) Version() (int64, error) {
+	const fn = "storage.migrator.Version"
+
+	var version int64
+
+	err := m.withConn(func(conn *sql.Conn) error {
+		versions, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		for v := range versions {
+			if v > version {
+				version = v
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return version, nil
+}
+
+// CurrentVersion reads the highest applied version without taking the
+// migration lock, which makes it cheap enough for health checks.
+func CurrentVersion(db *sql.DB) (int64, error) {
+	const fn = "storage.migrator.CurrentVersion"
+
+	var version int64
+	err := db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return version, nil
+}
+
+func (m *Migrator) withConn(f func(conn *sql.Conn) error) error {
+	ctx := context.Background()
+
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.lock != "" {
+		if _, err := conn.ExecContext(ctx, m.lock); err != nil {
+			return fmt.Errorf("acquire migration lock: %w", err)
+		}
+		defer conn.ExecContext(ctx, m.unlock)
+	}
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			applied_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	return f(conn)
+}
+
+func appliedVersions(conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(context.Background(), "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		versions[version] = appliedAt
+	}
+
+	return versions, rows.Err()
+}
+
+func inTx(conn *sql.Conn, f func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := f(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
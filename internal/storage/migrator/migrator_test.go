@@ -0,0 +1,159 @@
+package migrator
+
+import (
+	"database/sql"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func tableExists(t *testing.T, db *sql.DB, table string) bool {
+	t.Helper()
+
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?)", table).
+		Scan(&exists)
+	require.NoError(t, err)
+
+	return exists
+}
+
+var testMigrations = fstest.MapFS{
+	"0001_create_a.up.sql":   {Data: []byte("CREATE TABLE a (id INTEGER PRIMARY KEY);")},
+	"0001_create_a.down.sql": {Data: []byte("DROP TABLE a;")},
+	"0002_create_b.up.sql":   {Data: []byte("CREATE TABLE b (id INTEGER PRIMARY KEY); CREATE INDEX idx_b ON b(id);")},
+	"0002_create_b.down.sql": {Data: []byte("DROP TABLE b;")},
+}
+
+func TestMigrator_UpDownStatus(t *testing.T) {
+	db := newTestDB(t)
+
+	m, err := New(db, testMigrations)
+	require.NoError(t, err)
+
+	version, err := m.Version()
+	require.NoError(t, err)
+	require.Zero(t, version)
+
+	applied, err := m.Up()
+	require.NoError(t, err)
+	require.Len(t, applied, 2)
+	require.Equal(t, int64(1), applied[0].Version)
+	require.Equal(t, "create_b", applied[1].Name)
+	require.True(t, tableExists(t, db, "a"))
+	require.True(t, tableExists(t, db, "b"))
+
+	applied, err = m.Up()
+	require.NoError(t, err)
+	require.Empty(t, applied)
+
+	version, err = m.Version()
+	require.NoError(t, err)
+	require.Equal(t, int64(2), version)
+
+	version, err = CurrentVersion(db)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), version)
+
+	reverted, err := m.Down()
+	require.NoError(t, err)
+	require.Equal(t, int64(2), reverted.Version)
+	require.False(t, tableExists(t, db, "b"))
+	require.True(t, tableExists(t, db, "a"))
+
+	statuses, err := m.Status()
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	require.True(t, statuses[0].Applied)
+	require.False(t, statuses[0].AppliedAt.IsZero())
+	require.False(t, statuses[1].Applied)
+
+	_, err = m.Down()
+	require.NoError(t, err)
+
+	reverted, err = m.Down()
+	require.NoError(t, err)
+	require.Nil(t, reverted)
+}
+
+func TestMigrator_FailedMigrationIsRolledBack(t *testing.T) {
+	db := newTestDB(t)
+
+	m, err := New(db, fstest.MapFS{
+		"0001_ok.up.sql":     {Data: []byte("CREATE TABLE a (id INTEGER PRIMARY KEY);")},
+		"0002_broken.up.sql": {Data: []byte("CREATE TABLE b (id INTEGER PRIMARY KEY); INSERT INTO missing VALUES (1);")},
+	})
+	require.NoError(t, err)
+
+	applied, err := m.Up()
+	require.Error(t, err)
+	require.Len(t, applied, 1)
+
+	require.True(t, tableExists(t, db, "a"))
+	require.False(t, tableExists(t, db, "b"))
+
+	version, err := m.Version()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), version)
+}
+
+func TestMigrator_MissingDownScript(t *testing.T) {
+	db := newTestDB(t)
+
+	m, err := New(db, fstest.MapFS{
+		"0001_only_up.up.sql": {Data: []byte("CREATE TABLE a (id INTEGER PRIMARY KEY);")},
+	})
+	require.NoError(t, err)
+
+	_, err = m.Up()
+	require.NoError(t, err)
+
+	_, err = m.Down()
+	require.ErrorIs(t, err, ErrNoDownScript)
+	require.True(t, tableExists(t, db, "a"))
+}
+
+func TestNew_InvalidFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{
+			name: "Unexpected file name",
+			fsys: fstest.MapFS{"create_a.sql": {Data: []byte("SELECT 1;")}},
+		},
+		{
+			name: "Down without up",
+			fsys: fstest.MapFS{"0001_a.down.sql": {Data: []byte("SELECT 1;")}},
+		},
+		{
+			name: "Conflicting names",
+			fsys: fstest.MapFS{
+				"0001_a.up.sql": {Data: []byte("SELECT 1;")},
+				"0001_b.up.sql": {Data: []byte("SELECT 1;")},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := New(nil, tc.fsys)
+			require.Error(t, err)
+		})
+	}
+}
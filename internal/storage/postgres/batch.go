@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"url-shortener/internal/storage"
+)
+
+type batch struct {
+	tx *sql.Tx
+}
+
+func (s *Storage) BeginURLBatch() (storage.URLBatch, error) {
+	const fn = "storage.postgres.BeginURLBatch"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return &batch{tx: tx}, nil
+}
+
+func (b *batch) NextURLID() (int64, error) {
+	const fn = "storage.postgres.batch.NextURLID"
+
+	var id int64
+	if err := b.tx.QueryRow("SELECT nextval('url_id_seq')").Scan(&id); err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return id, nil
+}
+
+func (b *batch) SaveURL(urlToSave string, alias string, userId int64, opts storage.URLOptions) (int64, error) {
+	const fn = "storage.postgres.batch.SaveURL"
+
+	// Any error aborts a postgres transaction; rolling back to the savepoint
+	// keeps the rest of the batch alive.
+	if _, err := b.tx.Exec("SAVEPOINT link"); err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	id, err := insertURL(b.tx, urlToSave, alias, userId, opts)
+	if err != nil {
+		if _, rbErr := b.tx.Exec("ROLLBACK TO SAVEPOINT link"); rbErr != nil {
+			return 0, fmt.Errorf("%s: %w", fn, rbErr)
+		}
+
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if _, err := b.tx.Exec("RELEASE SAVEPOINT link"); err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return id, nil
+}
+
+func (b *batch) Commit() error {
+	const fn = "storage.postgres.batch.Commit"
+
+	if err := b.tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (b *batch) Rollback() error {
+	const fn = "storage.postgres.batch.Rollback"
+
+	if err := b.tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
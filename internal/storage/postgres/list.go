@@ -0,0 +1,193 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"url-shortener/internal/storage"
+)
+
+func (s *Storage) ListURLs(userId int64, q storage.URLQuery) ([]storage.URLInfo, error) {
+	const fn = "storage.postgres.ListURLs"
+
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	where := []string{"user_id = " + arg(userId)}
+
+	if q.Search != "" {
+		pattern := arg("%" + escapeLike(q.Search) + "%")
+		where = append(where, "(alias ILIKE "+pattern+" OR url ILIKE "+pattern+")")
+	}
+	if q.Tag != "" {
+		where = append(where, "id IN (SELECT url_id FROM url_tags WHERE tag = "+arg(q.Tag)+")")
+	}
+
+	switch q.Expiry {
+	case storage.ExpiryExpired:
+		where = append(where, "expires_at <= "+arg(time.Now()))
+	case storage.ExpiryExpiring:
+		where = append(where, "expires_at > "+arg(time.Now()))
+	case storage.ExpiryPermanent:
+		where = append(where, "expires_at IS NULL")
+	}
+
+	order := "created_at DESC NULLS LAST, id DESC"
+	if q.Sort == storage.SortClicks {
+		order = "clicks DESC, id DESC"
+		if q.After != nil {
+			where = append(where, "(clicks, id) < ("+arg(q.After.Clicks)+", "+arg(q.After.ID)+")")
+		}
+	} else if q.After != nil {
+		if q.After.CreatedAt.IsZero() {
+			where = append(where, "created_at IS NULL AND id < "+arg(q.After.ID))
+		} else {
+			where = append(where,
+				"((created_at, id) < ("+arg(q.After.CreatedAt)+", "+arg(q.After.ID)+") OR created_at IS NULL)")
+		}
+	}
+
+	rows, err := s.db.Query(`
+		SELECT id, alias, url, created_at, expires_at, clicks, COALESCE(max_clicks, 0), COALESCE(redirect_type, 0),
+			query_passthrough, path_passthrough
+		FROM url
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY `+order+` LIMIT `+arg(q.Limit),
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer rows.Close()
+
+	var urls []storage.URLInfo
+	for rows.Next() {
+		var u storage.URLInfo
+		var createdAt, expiresAt sql.NullTime
+		if err := rows.Scan(
+			&u.ID, &u.Alias, &u.URL, &createdAt, &expiresAt, &u.Clicks, &u.MaxClicks, &u.RedirectType,
+			&u.QueryPassthrough, &u.PathPassthrough,
+		); err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+		u.CreatedAt = createdAt.Time
+		u.ExpiresAt = expiresAt.Time
+		urls = append(urls, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := s.loadTags(urls); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return urls, nil
+}
+
+// loadTags fills in the tags of a page of links with a single query.
+func (s *Storage) loadTags(urls []storage.URLInfo) error {
+	if len(urls) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*storage.URLInfo, len(urls))
+	ids := make([]int64, len(urls))
+	for i := range urls {
+		byID[urls[i].ID] = &urls[i]
+		ids[i] = urls[i].ID
+	}
+
+	rows, err := s.db.Query("SELECT url_id, tag FROM url_tags WHERE url_id = ANY($1) ORDER BY tag", ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var urlId int64
+		var tag string
+		if err := rows.Scan(&urlId, &tag); err != nil {
+			return err
+		}
+		byID[urlId].Tags = append(byID[urlId].Tags, tag)
+	}
+
+	return rows.Err()
+}
+
+// escapeLike makes s match literally in a LIKE pattern; '\' is the default
+// escape character in postgres.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// walkPageSize is the number of links WalkURLs reads per query.
+const walkPageSize = 500
+
+func (s *Storage) WalkURLs(userId int64, visit func(storage.URLInfo) error) error {
+	const fn = "storage.postgres.WalkURLs"
+
+	// Each page is read in full before it is visited, so no read stays open
+	// while visit writes to a slow client.
+	var afterID int64
+	for {
+		page, err := s.walkPage(userId, afterID)
+		if err != nil {
+			return fmt.Errorf("%s: %w", fn, err)
+		}
+
+		for _, u := range page {
+			if err := visit(u); err != nil {
+				return err
+			}
+		}
+
+		if len(page) < walkPageSize {
+			return nil
+		}
+		afterID = page[len(page)-1].ID
+	}
+}
+
+func (s *Storage) walkPage(userId int64, afterID int64) ([]storage.URLInfo, error) {
+	rows, err := s.db.Query(`
+		SELECT id, alias, url, created_at, expires_at, clicks, COALESCE(max_clicks, 0), COALESCE(redirect_type, 0),
+			query_passthrough, path_passthrough,
+			COALESCE((SELECT json_agg(tag ORDER BY tag) FROM url_tags WHERE url_id = url.id), '[]')
+		FROM url WHERE user_id = $1 AND id > $2 ORDER BY id LIMIT $3`,
+		userId, afterID, walkPageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := make([]storage.URLInfo, 0, walkPageSize)
+	for rows.Next() {
+		var u storage.URLInfo
+		var createdAt, expiresAt sql.NullTime
+		var tags []byte
+		if err := rows.Scan(
+			&u.ID, &u.Alias, &u.URL, &createdAt, &expiresAt, &u.Clicks, &u.MaxClicks, &u.RedirectType,
+			&u.QueryPassthrough, &u.PathPassthrough, &tags,
+		); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(tags, &u.Tags); err != nil {
+			return nil, err
+		}
+		u.CreatedAt = createdAt.Time
+		u.ExpiresAt = expiresAt.Time
+
+		page = append(page, u)
+	}
+
+	return page, rows.Err()
+}
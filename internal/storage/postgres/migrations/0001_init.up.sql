@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS users (
+	id BIGSERIAL PRIMARY KEY,
+	username TEXT NOT NULL UNIQUE,
+	password TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS url (
+	id BIGSERIAL PRIMARY KEY,
+	url TEXT NOT NULL,
+	alias TEXT NOT NULL UNIQUE,
+	user_id BIGINT REFERENCES users(id)
+);
+CREATE INDEX IF NOT EXISTS idx_url_user_id ON url(user_id);
+
+CREATE TABLE IF NOT EXISTS sessions (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id),
+	token_hash TEXT NOT NULL UNIQUE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	expires_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON sessions(expires_at);
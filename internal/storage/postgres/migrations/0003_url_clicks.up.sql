@@ -0,0 +1,2 @@
+ALTER TABLE url ADD COLUMN IF NOT EXISTS clicks BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE url ADD COLUMN IF NOT EXISTS max_clicks BIGINT;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS clicks (
+	id BIGSERIAL PRIMARY KEY,
+	url_id BIGINT NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+	clicked_at TIMESTAMPTZ NOT NULL,
+	referrer TEXT NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	ip_hash TEXT NOT NULL DEFAULT '',
+	request_id TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_clicks_url_id_clicked_at ON clicks(url_id, clicked_at);
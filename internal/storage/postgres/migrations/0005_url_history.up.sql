@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS url_history (
+	id BIGSERIAL PRIMARY KEY,
+	url_id BIGINT NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+	old_url TEXT NOT NULL,
+	new_url TEXT NOT NULL,
+	changed_at TIMESTAMPTZ NOT NULL,
+	changed_by BIGINT REFERENCES users(id)
+);
+CREATE INDEX IF NOT EXISTS idx_url_history_url_id_changed_at ON url_history(url_id, changed_at);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS url_tags;
+DROP INDEX IF EXISTS idx_url_user_id_clicks;
+DROP INDEX IF EXISTS idx_url_user_id_id;
+ALTER TABLE url DROP COLUMN IF EXISTS created_at;
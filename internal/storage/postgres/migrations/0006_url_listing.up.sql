@@ -0,0 +1,12 @@
+-- Existing links keep a NULL creation time; new ones get the default.
+ALTER TABLE url ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ;
+ALTER TABLE url ALTER COLUMN created_at SET DEFAULT now();
+CREATE INDEX IF NOT EXISTS idx_url_user_id_id ON url(user_id, id);
+CREATE INDEX IF NOT EXISTS idx_url_user_id_clicks ON url(user_id, clicks, id);
+
+CREATE TABLE IF NOT EXISTS url_tags (
+	url_id BIGINT NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+	tag TEXT NOT NULL,
+	PRIMARY KEY (url_id, tag)
+);
+CREATE INDEX IF NOT EXISTS idx_url_tags_tag ON url_tags(tag, url_id);
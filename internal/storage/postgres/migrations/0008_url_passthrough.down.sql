@@ -0,0 +1,2 @@
+ALTER TABLE url DROP COLUMN IF EXISTS path_passthrough;
+ALTER TABLE url DROP COLUMN IF EXISTS query_passthrough;
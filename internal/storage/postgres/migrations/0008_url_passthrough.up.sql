@@ -0,0 +1,2 @@
+ALTER TABLE url ADD COLUMN IF NOT EXISTS query_passthrough TEXT NOT NULL DEFAULT '';
+ALTER TABLE url ADD COLUMN IF NOT EXISTS path_passthrough BOOLEAN NOT NULL DEFAULT FALSE;
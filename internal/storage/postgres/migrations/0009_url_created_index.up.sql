@@ -0,0 +1,2 @@
+-- Scanned backwards for created_at DESC NULLS LAST.
+CREATE INDEX IF NOT EXISTS idx_url_user_id_created ON url(user_id, created_at NULLS FIRST, id);
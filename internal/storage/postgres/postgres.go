@@ -0,0 +1,570 @@
+package postgres
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"io/fs"
+	"time"
+	"url-shortener/internal/lib/security"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/migrator"
+)
+
+// SQLSTATE unique_violation.
+const uniqueViolation = "23505"
+
+// Arbitrary key of the advisory lock held while migrating.
+const migrationLockID = 7_203_118_001
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+var _ storage.Store = (*Storage)(nil)
+
+type Storage struct {
+	db *sql.DB
+}
+
+func New(dsn string) (*Storage, error) {
+	const fn = "storage.postgres.New"
+
+	s, err := Open(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	m, err := s.Migrator()
+	if err != nil {
+		s.db.Close()
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if _, err := m.Up(); err != nil {
+		s.db.Close()
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return s, nil
+}
+
+// Open connects to the database without touching its schema.
+func Open(dsn string) (*Storage, error) {
+	const fn = "storage.postgres.Open"
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return &Storage{db: db}, nil
+}
+
+// Migrator returns the schema migrator of the database. Migrations hold an
+// advisory lock so that replicas starting together apply them only once.
+func (s *Storage) Migrator() (*migrator.Migrator, error) {
+	const fn = "storage.postgres.Migrator"
+
+	fsys, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	m, err := migrator.New(s.db, fsys, migrator.WithLock(
+		fmt.Sprintf("SELECT pg_advisory_lock(%d)", migrationLockID),
+		fmt.Sprintf("SELECT pg_advisory_unlock(%d)", migrationLockID),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return m, nil
+}
+
+func (s *Storage) Ping() error {
+	return s.db.Ping()
+}
+
+func (s *Storage) SchemaVersion() (int64, error) {
+	return migrator.CurrentVersion(s.db)
+}
+
+func (s *Storage) Close() error {
+	return s.db.Close()
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+func nullInt64(n int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: n, Valid: n != 0}
+}
+
+func (s *Storage) NextURLID() (int64, error) {
+	const fn = "storage.postgres.NextURLID"
+
+	var id int64
+	if err := s.db.QueryRow("SELECT nextval('url_id_seq')").Scan(&id); err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) SaveURL(urlToSave string, alias string, userId int64, opts storage.URLOptions) (int64, error) {
+	const fn = "storage.postgres.SaveURL"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer tx.Rollback()
+
+	id, err := insertURL(tx, urlToSave, alias, userId, opts)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return id, nil
+}
+
+// insertURL inserts a link and its tags, taking an id from the sequence
+// unless one was reserved.
+func insertURL(tx *sql.Tx, urlToSave string, alias string, userId int64, opts storage.URLOptions) (int64, error) {
+	var id int64
+	err := tx.QueryRow(
+		"INSERT INTO url(id, url, alias, user_id, expires_at, max_clicks, created_at, clicks, redirect_type, "+
+			"query_passthrough, path_passthrough) "+
+			"VALUES(COALESCE($1, nextval('url_id_seq')), $2, $3, $4, $5, $6, COALESCE($7, now()), $8, $9, $10, $11) RETURNING id",
+		nullInt64(opts.ID), urlToSave, alias, userId, nullTime(opts.ExpiresAt), nullInt64(opts.MaxClicks),
+		nullTime(opts.CreatedAt), opts.Clicks, nullInt64(int64(opts.RedirectType)),
+		opts.QueryPassthrough, opts.PathPassthrough,
+	).Scan(&id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, storage.ErrURLExists
+		}
+
+		return 0, err
+	}
+
+	for _, tag := range opts.Tags {
+		if _, err := tx.Exec("INSERT INTO url_tags(url_id, tag) VALUES($1, $2) ON CONFLICT DO NOTHING", id, tag); err != nil {
+			return 0, err
+		}
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetURL(alias string) (string, error) {
+	const fn = "storage.postgres.GetURL"
+
+	var resURL string
+	var expiresAt sql.NullTime
+	err := s.db.QueryRow("SELECT url, expires_at FROM url WHERE alias = $1", alias).Scan(&resURL, &expiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("%s: %w", fn, storage.ErrURLNotFound)
+		}
+
+		return "", fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if expiresAt.Valid && !expiresAt.Time.After(time.Now()) {
+		return "", fmt.Errorf("%s: %w", fn, storage.ErrURLExpired)
+	}
+
+	return resURL, nil
+}
+
+func (s *Storage) HitURL(alias string, subPath bool) (storage.URLTarget, error) {
+	const fn = "storage.postgres.HitURL"
+
+	// Concurrent UPDATEs of the same row are serialized and the WHERE clause
+	// is re-checked after the lock, so clicks never exceed max_clicks.
+	var target storage.URLTarget
+	var redirectType sql.NullInt64
+	var expiresAt sql.NullTime
+	err := s.db.QueryRow(`
+		UPDATE url SET clicks = clicks + 1
+		WHERE alias = $1
+			AND (expires_at IS NULL OR expires_at > $2)
+			AND (max_clicks IS NULL OR clicks < max_clicks)
+			AND (NOT $3 OR path_passthrough)
+		RETURNING url, redirect_type, expires_at, COALESCE(max_clicks, 0), query_passthrough, path_passthrough`, alias, time.Now(), subPath).Scan(
+		&target.URL, &redirectType, &expiresAt, &target.MaxClicks, &target.QueryPassthrough, &target.PathPassthrough,
+	)
+	if err == nil {
+		target.RedirectType = int(redirectType.Int64)
+		target.ExpiresAt = expiresAt.Time
+		return target, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return storage.URLTarget{}, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return storage.URLTarget{}, fmt.Errorf("%s: %w", fn, s.hitError(alias, subPath))
+}
+
+// hitError tells why HitURL found nothing to count: the link is missing,
+// takes no sub-paths, expired or is used up.
+func (s *Storage) hitError(alias string, subPath bool) error {
+	var pathPassthrough bool
+	var expiresAt sql.NullTime
+	err := s.db.QueryRow("SELECT path_passthrough, expires_at FROM url WHERE alias = $1", alias).Scan(&pathPassthrough, &expiresAt)
+	switch {
+	case errors.Is(err, sql.ErrNoRows), err == nil && subPath && !pathPassthrough:
+		return storage.ErrURLNotFound
+	case err != nil:
+		return err
+	case expiresAt.Valid && !expiresAt.Time.After(time.Now()):
+		return storage.ErrURLExpired
+	default:
+		return storage.ErrURLExhausted
+	}
+}
+
+func (s *Storage) DeleteURL(alias string, userId int64) error {
+	const fn = "storage.postgres.DeleteURL"
+
+	res, err := s.db.Exec("DELETE FROM url WHERE alias = $1 AND user_id = $2", alias, userId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	if affected > 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%s: %w", fn, s.urlAccessError(alias))
+}
+
+func (s *Storage) ReplaceURL(urlToSave string, alias string, userId int64, opts storage.URLOptions) (int64, error) {
+	const fn = "storage.postgres.ReplaceURL"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("DELETE FROM url WHERE alias = $1 AND user_id = $2", alias, userId)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+	if affected == 0 {
+		tx.Rollback()
+		return 0, fmt.Errorf("%s: %w", fn, s.urlAccessError(alias))
+	}
+
+	id, err := insertURL(tx, urlToSave, alias, userId, opts)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return id, nil
+}
+
+// urlAccessError explains why a query scoped to the owner of alias matched
+// nothing: the alias is missing or the link belongs to someone else.
+func (s *Storage) urlAccessError(alias string) error {
+	var exists bool
+	err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM url WHERE alias = $1)", alias).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return storage.ErrURLNotFound
+	}
+
+	return storage.ErrForbidden
+}
+
+func (s *Storage) UpdateURL(alias string, userId int64, upd storage.URLUpdate) error {
+	const fn = "storage.postgres.UpdateURL"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	defer tx.Rollback()
+
+	var urlId int64
+	var oldURL string
+	err = tx.QueryRow(
+		"SELECT id, url FROM url WHERE alias = $1 AND user_id = $2 FOR UPDATE",
+		alias, userId,
+	).Scan(&urlId, &oldURL)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			tx.Rollback()
+			return fmt.Errorf("%s: %w", fn, s.urlAccessError(alias))
+		}
+
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	var expiresAt sql.NullTime
+	if upd.ExpiresAt != nil {
+		expiresAt = nullTime(*upd.ExpiresAt)
+	}
+	var redirectType sql.NullInt64
+	if upd.RedirectType != nil {
+		redirectType = nullInt64(int64(*upd.RedirectType))
+	}
+
+	_, err = tx.Exec(`
+		UPDATE url SET
+			url = COALESCE($1, url),
+			expires_at = CASE WHEN $2 THEN $3 ELSE expires_at END,
+			redirect_type = CASE WHEN $4 THEN $5 ELSE redirect_type END,
+			query_passthrough = COALESCE($6, query_passthrough),
+			path_passthrough = COALESCE($7, path_passthrough)
+		WHERE id = $8`,
+		upd.URL, upd.ExpiresAt != nil, expiresAt, upd.RedirectType != nil, redirectType,
+		upd.QueryPassthrough, upd.PathPassthrough, urlId,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if upd.URL != nil && *upd.URL != oldURL {
+		_, err := tx.Exec(
+			"INSERT INTO url_history(url_id, old_url, new_url, changed_at, changed_by) VALUES($1, $2, $3, $4, $5)",
+			urlId, oldURL, *upd.URL, time.Now(), userId,
+		)
+		if err != nil {
+			return fmt.Errorf("%s: %w", fn, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetURLHistory(alias string, userId int64) ([]storage.URLChange, error) {
+	const fn = "storage.postgres.GetURLHistory"
+
+	var urlId int64
+	var ownerId sql.NullInt64
+	err := s.db.QueryRow("SELECT id, user_id FROM url WHERE alias = $1", alias).Scan(&urlId, &ownerId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", fn, storage.ErrURLNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	if ownerId.Int64 != userId {
+		return nil, fmt.Errorf("%s: %w", fn, storage.ErrForbidden)
+	}
+
+	rows, err := s.db.Query(`
+		SELECT old_url, new_url, changed_at, COALESCE(changed_by, 0) FROM url_history
+		WHERE url_id = $1 ORDER BY changed_at DESC, id DESC`,
+		urlId,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer rows.Close()
+
+	var history []storage.URLChange
+	for rows.Next() {
+		var c storage.URLChange
+		if err := rows.Scan(&c.OldURL, &c.NewURL, &c.ChangedAt, &c.ChangedBy); err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+		history = append(history, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return history, nil
+}
+
+func (s *Storage) DeleteExpiredURLs(before time.Time) (int64, error) {
+	const fn = "storage.postgres.DeleteExpiredURLs"
+
+	res, err := s.db.Exec("DELETE FROM url WHERE expires_at <= $1", before)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return deleted, nil
+}
+
+func (s *Storage) CreateUser(username string, password string) (int64, error) {
+	const fn = "storage.postgres.CreateUser"
+
+	hashedPassword, err := security.HashPassword(password)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	var id int64
+	err = s.db.QueryRow(
+		"INSERT INTO users(username, password) VALUES($1, $2) RETURNING id",
+		username, hashedPassword,
+	).Scan(&id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, fmt.Errorf("%s: %w", fn, storage.ErrUserExists)
+		}
+
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) AuthenticateUser(username string, password string) (int64, error) {
+	const fn = "storage.postgres.AuthenticateUser"
+
+	var userId int64
+	var hashedPassword string
+
+	err := s.db.QueryRow("SELECT id, password FROM users WHERE username = $1", username).
+		Scan(&userId, &hashedPassword)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%s: %w", fn, storage.ErrUserNotFound)
+		}
+
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	auth := security.VerifyPassword(password, hashedPassword)
+	if !auth {
+		return 0, nil
+	}
+
+	return userId, nil
+}
+
+func (s *Storage) CreateSession(userId int64, token string, expiresAt time.Time) (int64, error) {
+	const fn = "storage.postgres.CreateSession"
+
+	var id int64
+	err := s.db.QueryRow(
+		"INSERT INTO sessions (user_id, token_hash, expires_at) VALUES ($1, $2, $3) RETURNING id",
+		userId, security.HashToken(token), expiresAt,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetSessionUserID(token string) (int64, error) {
+	const fn = "storage.postgres.GetSessionUserID"
+
+	var userId int64
+	err := s.db.QueryRow(
+		"SELECT user_id FROM sessions WHERE token_hash = $1 AND expires_at > $2",
+		security.HashToken(token), time.Now(),
+	).Scan(&userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%s: %w", fn, storage.ErrSessionNotFound)
+		}
+
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return userId, nil
+}
+
+func (s *Storage) ExtendSession(token string, expiresAt time.Time) error {
+	const fn = "storage.postgres.ExtendSession"
+
+	_, err := s.db.Exec(
+		"UPDATE sessions SET expires_at = $1 WHERE token_hash = $2",
+		expiresAt, security.HashToken(token),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteSession(token string) error {
+	const fn = "storage.postgres.DeleteSession"
+
+	_, err := s.db.Exec("DELETE FROM sessions WHERE token_hash = $1", security.HashToken(token))
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteUserSessions(userId int64) error {
+	const fn = "storage.postgres.DeleteUserSessions"
+
+	_, err := s.db.Exec("DELETE FROM sessions WHERE user_id = $1", userId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteExpiredSessions() (int64, error) {
+	const fn = "storage.postgres.DeleteExpiredSessions"
+
+	res, err := s.db.Exec("DELETE FROM sessions WHERE expires_at IS NULL OR expires_at <= $1", time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,86 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"url-shortener/internal/storage"
+)
+
+type batch struct {
+	tx *sql.Tx
+}
+
+func (s *Storage) BeginURLBatch() (storage.URLBatch, error) {
+	const fn = "storage.sqlite.BeginURLBatch"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return &batch{tx: tx}, nil
+}
+
+func (b *batch) NextURLID() (int64, error) {
+	const fn = "storage.sqlite.batch.NextURLID"
+
+	id, err := nextURLID(b.tx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return id, nil
+}
+
+func (b *batch) SaveURL(urlToSave string, alias string, userId int64, opts storage.URLOptions) (int64, error) {
+	const fn = "storage.sqlite.batch.SaveURL"
+
+	id := opts.ID
+	if id == 0 {
+		var err error
+		if id, err = b.NextURLID(); err != nil {
+			return 0, fmt.Errorf("%s: %w", fn, err)
+		}
+	}
+
+	// sqlite only undoes the failing statement, so without the savepoint a
+	// link whose tags fail would be kept without them.
+	if _, err := b.tx.Exec("SAVEPOINT link"); err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := insertURL(b.tx, id, urlToSave, alias, userId, opts); err != nil {
+		if _, rbErr := b.tx.Exec("ROLLBACK TO link"); rbErr != nil {
+			return 0, fmt.Errorf("%s: %w", fn, rbErr)
+		}
+
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if _, err := b.tx.Exec("RELEASE link"); err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return id, nil
+}
+
+func (b *batch) Commit() error {
+	const fn = "storage.sqlite.batch.Commit"
+
+	if err := b.tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (b *batch) Rollback() error {
+	const fn = "storage.sqlite.batch.Rollback"
+
+	if err := b.tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
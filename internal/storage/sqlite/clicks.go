@@ -0,0 +1,97 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"url-shortener/internal/storage"
+)
+
+func (s *Storage) SaveClicks(clicks []storage.Click) error {
+	const fn = "storage.sqlite.SaveClicks"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	defer tx.Rollback()
+
+	query, err := tx.Prepare(`
+		INSERT INTO clicks(url_id, clicked_at, referrer, user_agent, ip_hash, request_id)
+		SELECT id, ?, ?, ?, ?, ? FROM url WHERE alias = ?`)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	defer query.Close()
+
+	for _, c := range clicks {
+		_, err := query.Exec(c.At.UTC(), c.Referrer, c.UserAgent, c.IPHash, c.RequestID, c.Alias)
+		if err != nil {
+			return fmt.Errorf("%s: %w", fn, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetURLStats(alias string, userId int64) (storage.URLStats, error) {
+	const fn = "storage.sqlite.GetURLStats"
+
+	var stats storage.URLStats
+
+	var urlId int64
+	var ownerId sql.NullInt64
+	err := s.db.QueryRow("SELECT id, user_id FROM url WHERE alias = ?", alias).Scan(&urlId, &ownerId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return stats, fmt.Errorf("%s: %w", fn, storage.ErrURLNotFound)
+		}
+
+		return stats, fmt.Errorf("%s: %w", fn, err)
+	}
+	if ownerId.Int64 != userId {
+		return stats, fmt.Errorf("%s: %w", fn, storage.ErrForbidden)
+	}
+
+	err = s.db.QueryRow(
+		"SELECT COUNT(*), COUNT(DISTINCT NULLIF(ip_hash, '')) FROM clicks WHERE url_id = ?",
+		urlId,
+	).Scan(&stats.TotalClicks, &stats.UniqueVisitors)
+	if err != nil {
+		return stats, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	rows, err := s.db.Query(
+		"SELECT date(clicked_at), COUNT(*) FROM clicks WHERE url_id = ? GROUP BY 1 ORDER BY 1",
+		urlId,
+	)
+	if err != nil {
+		return stats, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var day string
+		var clicks int64
+		if err := rows.Scan(&day, &clicks); err != nil {
+			return stats, fmt.Errorf("%s: %w", fn, err)
+		}
+
+		date, err := time.Parse(time.DateOnly, day)
+		if err != nil {
+			return stats, fmt.Errorf("%s: %w", fn, err)
+		}
+
+		stats.Daily = append(stats.Daily, storage.DailyClicks{Date: date, Clicks: clicks})
+	}
+	if err := rows.Err(); err != nil {
+		return stats, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,143 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"url-shortener/internal/lib/security"
+)
+
+// upgradeLegacySchema brings a database created before versioned migrations
+// existed to the shape of migration 0001, which is then merely recorded as
+// applied. Databases that already have schema_migrations are left alone.
+func upgradeLegacySchema(db *sql.DB) error {
+	versioned, err := hasTable(db, "schema_migrations")
+	if err != nil || versioned {
+		return err
+	}
+
+	// Databases created before links had owners lack the user_id column.
+	err = addColumnIfMissing(db, "url", "user_id", "INTEGER REFERENCES users(id)")
+	if err != nil {
+		return err
+	}
+
+	// Sessions created before expiry existed get a NULL expires_at and are
+	// therefore treated as expired.
+	err = addColumnIfMissing(db, "sessions", "expires_at", "TIMESTAMP")
+	if err != nil {
+		return err
+	}
+
+	return hashSessionTokens(db)
+}
+
+func hasTable(db *sql.DB, table string) (bool, error) {
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?)", table).Scan(&exists)
+
+	return exists, err
+}
+
+func hasColumn(db *sql.DB, table string, column string) (bool, error) {
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM pragma_table_info(?) WHERE name = ?)", table, column).Scan(&exists)
+
+	return exists, err
+}
+
+// addColumnIfMissing adds the column to an existing table. Missing tables
+// are left for the migrations to create.
+func addColumnIfMissing(db *sql.DB, table string, column string, definition string) error {
+	tableExists, err := hasTable(db, table)
+	if err != nil || !tableExists {
+		return err
+	}
+
+	exists, err := hasColumn(db, table, column)
+	if err != nil || exists {
+		return err
+	}
+
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+
+	return err
+}
+
+// hashSessionTokens upgrades a sessions table that still keeps plaintext
+// tokens: the table is rebuilt with a token_hash column holding the SHA-256
+// digest of every existing token, so issued tokens keep working.
+func hashSessionTokens(db *sql.DB) error {
+	legacy, err := hasColumn(db, "sessions", "token")
+	if err != nil || !legacy {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	type session struct {
+		id        int64
+		userId    int64
+		token     string
+		createdAt sql.NullTime
+		expiresAt sql.NullTime
+	}
+
+	rows, err := tx.Query("SELECT id, user_id, token, created_at, expires_at FROM sessions")
+	if err != nil {
+		return err
+	}
+
+	var sessions []session
+	for rows.Next() {
+		var s session
+		if err := rows.Scan(&s.id, &s.userId, &s.token, &s.createdAt, &s.expiresAt); err != nil {
+			rows.Close()
+			return err
+		}
+		sessions = append(sessions, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		CREATE TABLE sessions_hashed (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		);
+	`)
+	if err != nil {
+		return err
+	}
+
+	insert, err := tx.Prepare(
+		"INSERT INTO sessions_hashed (id, user_id, token_hash, created_at, expires_at) VALUES (?, ?, ?, ?, ?)",
+	)
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+
+	for _, s := range sessions {
+		_, err = insert.Exec(s.id, s.userId, security.HashToken(s.token), s.createdAt, s.expiresAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec("DROP TABLE sessions; ALTER TABLE sessions_hashed RENAME TO sessions;")
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS url (
+	id INTEGER PRIMARY KEY,
+	url TEXT NOT NULL,
+	alias TEXT NOT NULL UNIQUE,
+	user_id INTEGER REFERENCES users(id)
+);
+CREATE INDEX IF NOT EXISTS idx_alias ON url(alias);
+CREATE INDEX IF NOT EXISTS idx_url_user_id ON url(user_id);
+
+CREATE TABLE IF NOT EXISTS users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username TEXT NOT NULL UNIQUE,
+	password TEXT NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	expires_at TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON sessions(expires_at);
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS trg_url_delete_clicks;
+DROP TABLE IF EXISTS clicks;
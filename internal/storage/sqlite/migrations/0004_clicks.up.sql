@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS clicks (
+	id INTEGER PRIMARY KEY,
+	url_id INTEGER NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+	clicked_at TIMESTAMP NOT NULL,
+	referrer TEXT NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	ip_hash TEXT NOT NULL DEFAULT '',
+	request_id TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_clicks_url_id_clicked_at ON clicks(url_id, clicked_at);
+
+-- Foreign keys are not enforced on our connections, so cascade by hand.
+CREATE TRIGGER IF NOT EXISTS trg_url_delete_clicks AFTER DELETE ON url
+BEGIN
+	DELETE FROM clicks WHERE url_id = OLD.id;
+END;
@@ -0,0 +1,7 @@
+-- url ids are handed out from this sequence so that they can be reserved
+-- before insert and are never reused after a delete.
+CREATE TABLE IF NOT EXISTS url_id_seq (
+	id INTEGER PRIMARY KEY AUTOINCREMENT
+);
+INSERT INTO url_id_seq(id) SELECT COALESCE(MAX(id), 0) FROM url;
+DELETE FROM url_id_seq;
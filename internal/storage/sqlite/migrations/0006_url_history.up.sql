@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS url_history (
+	id INTEGER PRIMARY KEY,
+	url_id INTEGER NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+	old_url TEXT NOT NULL,
+	new_url TEXT NOT NULL,
+	changed_at TIMESTAMP NOT NULL,
+	changed_by INTEGER REFERENCES users(id)
+);
+CREATE INDEX IF NOT EXISTS idx_url_history_url_id_changed_at ON url_history(url_id, changed_at);
+
+CREATE TRIGGER IF NOT EXISTS trg_url_delete_history AFTER DELETE ON url
+BEGIN
+	DELETE FROM url_history WHERE url_id = OLD.id;
+END;
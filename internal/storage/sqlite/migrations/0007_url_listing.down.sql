@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS trg_url_delete_tags;
+DROP TABLE IF EXISTS url_tags;
+DROP INDEX IF EXISTS idx_url_user_id_clicks;
+ALTER TABLE url DROP COLUMN created_at;
@@ -0,0 +1,15 @@
+ALTER TABLE url ADD COLUMN created_at TIMESTAMP;
+-- idx_url_user_id already covers listing by id: sqlite appends the rowid.
+CREATE INDEX IF NOT EXISTS idx_url_user_id_clicks ON url(user_id, clicks, id);
+
+CREATE TABLE IF NOT EXISTS url_tags (
+	url_id INTEGER NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+	tag TEXT NOT NULL,
+	PRIMARY KEY (url_id, tag)
+);
+CREATE INDEX IF NOT EXISTS idx_url_tags_tag ON url_tags(tag, url_id);
+
+CREATE TRIGGER IF NOT EXISTS trg_url_delete_tags AFTER DELETE ON url
+BEGIN
+	DELETE FROM url_tags WHERE url_id = OLD.id;
+END;
@@ -0,0 +1,2 @@
+ALTER TABLE url DROP COLUMN path_passthrough;
+ALTER TABLE url DROP COLUMN query_passthrough;
@@ -0,0 +1,2 @@
+ALTER TABLE url ADD COLUMN query_passthrough TEXT NOT NULL DEFAULT '';
+ALTER TABLE url ADD COLUMN path_passthrough BOOLEAN NOT NULL DEFAULT 0;
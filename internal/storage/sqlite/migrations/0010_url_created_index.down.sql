@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_url_user_id_created;
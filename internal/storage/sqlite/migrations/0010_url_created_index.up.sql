@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_url_user_id_created ON url(user_id, created_at, id);
@@ -2,14 +2,23 @@ package sqlite
 
 import (
 	"database/sql"
+	"embed"
 	"errors"
 	"fmt"
 	"github.com/mattn/go-sqlite3"
 	_ "github.com/mattn/go-sqlite3"
+	"io/fs"
+	"time"
 	"url-shortener/internal/lib/security"
 	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/migrator"
 )
 
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+var _ storage.Store = (*Storage)(nil)
+
 type Storage struct {
 	db *sql.DB
 }
@@ -17,89 +26,187 @@ type Storage struct {
 func New(storagePath string) (*Storage, error) {
 	const fn = "storage.sqlite.New"
 
-	db, err := sql.Open("sqlite3", storagePath)
+	s, err := Open(storagePath)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", fn, err)
 	}
 
-	queries := []string{
-		`
-		CREATE TABLE IF NOT EXISTS url (
-			id INTEGER PRIMARY KEY,
-			url TEXT NOT NULL,
-			alias TEXT NOT NULL UNIQUE
-		);
-		CREATE INDEX IF NOT EXISTS idx_alias ON url(alias);
-		`,
-		`
-		CREATE TABLE IF NOT EXISTS users (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			username TEXT NOT NULL UNIQUE,
-			password TEXT NOT NULL,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		);
-		`,
-		`
-		CREATE TABLE IF NOT EXISTS sessions (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			user_id INTEGER NOT NULL,
-			token TEXT NOT NULL UNIQUE,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (user_id) REFERENCES users(id)
-		);
-		`,
-	}
-
-	for _, query := range queries {
-		query, err := db.Prepare(query)
-		if err != nil {
-			return nil, fmt.Errorf("%s: %w", fn, err)
-		}
-		_, err = query.Exec()
-		if err != nil {
-			return nil, fmt.Errorf("%s: %w", fn, err)
-		}
+	m, err := s.Migrator()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if _, err := m.Up(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return s, nil
+}
+
+// Open opens the database without touching its schema.
+func Open(storagePath string) (*Storage, error) {
+	const fn = "storage.sqlite.Open"
+
+	db, err := sql.Open("sqlite3", storagePath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
 	}
 
 	return &Storage{db: db}, nil
 }
 
-func (s *Storage) SaveURL(urlToSave string, alias string) (int64, error) {
+// Migrator returns the schema migrator of the database. A database created
+// before versioned migrations is first upgraded to the shape of the initial
+// migration.
+func (s *Storage) Migrator() (*migrator.Migrator, error) {
+	const fn = "storage.sqlite.Migrator"
+
+	if err := upgradeLegacySchema(s.db); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	fsys, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	m, err := migrator.New(s.db, fsys)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return m, nil
+}
+
+func (s *Storage) Ping() error {
+	return s.db.Ping()
+}
+
+func (s *Storage) SchemaVersion() (int64, error) {
+	return migrator.CurrentVersion(s.db)
+}
+
+func (s *Storage) Close() error {
+	return s.db.Close()
+}
+
+// nullTime stores zero times as NULL and everything else in UTC, which keeps
+// the textual timestamps comparable.
+func nullTime(t time.Time) sql.NullTime {
+	if t.IsZero() {
+		return sql.NullTime{}
+	}
+
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+func nullInt64(n int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: n, Valid: n != 0}
+}
+
+func (s *Storage) NextURLID() (int64, error) {
+	const fn = "storage.sqlite.NextURLID"
+
+	id, err := nextURLID(s.db)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return id, nil
+}
+
+// querier is what *sql.DB and *sql.Tx have in common.
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func nextURLID(q querier) (int64, error) {
+	// AUTOINCREMENT keeps counting in sqlite_sequence after the row is gone.
+	var id int64
+	if err := q.QueryRow("INSERT INTO url_id_seq DEFAULT VALUES RETURNING id").Scan(&id); err != nil {
+		return 0, err
+	}
+
+	if _, err := q.Exec("DELETE FROM url_id_seq WHERE id <= ?", id); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (s *Storage) SaveURL(urlToSave string, alias string, userId int64, opts storage.URLOptions) (int64, error) {
 	const fn = "storage.sqlite.SaveURL"
 
-	query, err := s.db.Prepare("INSERT INTO url(url, alias) VALUES(?, ?)")
+	// Take every id from the sequence, so that plain inserts never claim an
+	// id reserved by someone else.
+	id := opts.ID
+	if id == 0 {
+		var err error
+		if id, err = s.NextURLID(); err != nil {
+			return 0, fmt.Errorf("%s: %w", fn, err)
+		}
+	}
+
+	tx, err := s.db.Begin()
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", fn, err)
 	}
+	defer tx.Rollback()
 
-	res, err := query.Exec(urlToSave, alias)
+	if err := insertURL(tx, id, urlToSave, alias, userId, opts); err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return id, nil
+}
+
+// insertURL inserts a link and its tags under the given id.
+func insertURL(q querier, id int64, urlToSave string, alias string, userId int64, opts storage.URLOptions) error {
+	createdAt := opts.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	_, err := q.Exec(
+		`INSERT INTO url(id, url, alias, user_id, expires_at, max_clicks, created_at, clicks, redirect_type,
+			query_passthrough, path_passthrough) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, urlToSave, alias, userId, nullTime(opts.ExpiresAt), nullInt64(opts.MaxClicks), createdAt.UTC(), opts.Clicks,
+		nullInt64(int64(opts.RedirectType)), opts.QueryPassthrough, opts.PathPassthrough,
+	)
 	if err != nil {
 		var sqliteErr sqlite3.Error
 		if errors.As(err, &sqliteErr) && errors.Is(sqliteErr.ExtendedCode, sqlite3.ErrConstraintUnique) {
-			return 0, fmt.Errorf("%s: %w", fn, storage.ErrURLExists)
+			return storage.ErrURLExists
 		}
 
-		return 0, fmt.Errorf("%s: %w", fn, err)
+		return err
 	}
 
-	id, err := res.LastInsertId()
-	if err != nil {
-		return 0, fmt.Errorf("%s: %w", fn, err)
+	for _, tag := range opts.Tags {
+		if _, err := q.Exec("INSERT OR IGNORE INTO url_tags(url_id, tag) VALUES(?, ?)", id, tag); err != nil {
+			return err
+		}
 	}
 
-	return id, nil
+	return nil
 }
 
 func (s *Storage) GetURL(alias string) (string, error) {
 	const fn = "storage.sqlite.GetURL"
 
-	query, err := s.db.Prepare("SELECT url FROM url WHERE alias = ?")
+	query, err := s.db.Prepare("SELECT url, expires_at FROM url WHERE alias = ?")
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", fn, err)
 	}
 
 	var resURL string
-	err = query.QueryRow(alias).Scan(&resURL)
+	var expiresAt sql.NullTime
+	err = query.QueryRow(alias).Scan(&resURL, &expiresAt)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -109,43 +216,290 @@ func (s *Storage) GetURL(alias string) (string, error) {
 		return "", fmt.Errorf("%s: %w", fn, err)
 	}
 
+	if expiresAt.Valid && !expiresAt.Time.After(time.Now()) {
+		return "", fmt.Errorf("%s: %w", fn, storage.ErrURLExpired)
+	}
+
 	return resURL, nil
 }
 
-func (s *Storage) DeleteURL(alias string) error {
+func (s *Storage) HitURL(alias string, subPath bool) (storage.URLTarget, error) {
+	const fn = "storage.sqlite.HitURL"
+
+	// A single conditional UPDATE is atomic, so concurrent redirects can never
+	// push clicks past max_clicks.
+	query, err := s.db.Prepare(`
+		UPDATE url SET clicks = clicks + 1
+		WHERE alias = ?
+			AND (expires_at IS NULL OR expires_at > ?)
+			AND (max_clicks IS NULL OR clicks < max_clicks)
+			AND (NOT ? OR path_passthrough)
+		RETURNING url, redirect_type, expires_at, COALESCE(max_clicks, 0), query_passthrough, path_passthrough`)
+	if err != nil {
+		return storage.URLTarget{}, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	var target storage.URLTarget
+	var redirectType sql.NullInt64
+	var expiresAt sql.NullTime
+	err = query.QueryRow(alias, time.Now().UTC(), subPath).Scan(
+		&target.URL, &redirectType, &expiresAt, &target.MaxClicks, &target.QueryPassthrough, &target.PathPassthrough,
+	)
+	if err == nil {
+		target.RedirectType = int(redirectType.Int64)
+		target.ExpiresAt = expiresAt.Time
+		return target, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return storage.URLTarget{}, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return storage.URLTarget{}, fmt.Errorf("%s: %w", fn, s.hitError(alias, subPath))
+}
+
+// hitError tells why HitURL found nothing to count: the link is missing,
+// takes no sub-paths, expired or is used up.
+func (s *Storage) hitError(alias string, subPath bool) error {
+	var pathPassthrough bool
+	var expiresAt sql.NullTime
+	err := s.db.QueryRow("SELECT path_passthrough, expires_at FROM url WHERE alias = ?", alias).Scan(&pathPassthrough, &expiresAt)
+	switch {
+	case errors.Is(err, sql.ErrNoRows), err == nil && subPath && !pathPassthrough:
+		return storage.ErrURLNotFound
+	case err != nil:
+		return err
+	case expiresAt.Valid && !expiresAt.Time.After(time.Now()):
+		return storage.ErrURLExpired
+	default:
+		return storage.ErrURLExhausted
+	}
+}
+
+func (s *Storage) DeleteURL(alias string, userId int64) error {
 	const fn = "storage.sqlite.DeleteURL"
 
-	query, err := s.db.Prepare("DELETE FROM url WHERE alias = ?")
+	query, err := s.db.Prepare("DELETE FROM url WHERE alias = ? AND user_id = ?")
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	res, err := query.Exec(alias, userId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	if affected > 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%s: %w", fn, s.urlAccessError(alias))
+}
+
+func (s *Storage) ReplaceURL(urlToSave string, alias string, userId int64, opts storage.URLOptions) (int64, error) {
+	const fn = "storage.sqlite.ReplaceURL"
+
+	id := opts.ID
+	if id == 0 {
+		var err error
+		if id, err = s.NextURLID(); err != nil {
+			return 0, fmt.Errorf("%s: %w", fn, err)
+		}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("DELETE FROM url WHERE alias = ? AND user_id = ?", alias, userId)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+	if affected == 0 {
+		tx.Rollback()
+		return 0, fmt.Errorf("%s: %w", fn, s.urlAccessError(alias))
+	}
+
+	if err := insertURL(tx, id, urlToSave, alias, userId, opts); err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return id, nil
+}
+
+// urlAccessError explains why a query scoped to the owner of alias matched
+// nothing: the alias is missing or the link belongs to someone else.
+func (s *Storage) urlAccessError(alias string) error {
+	var exists bool
+	err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM url WHERE alias = ?)", alias).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return storage.ErrURLNotFound
+	}
+
+	return storage.ErrForbidden
+}
+
+func (s *Storage) UpdateURL(alias string, userId int64, upd storage.URLUpdate) error {
+	const fn = "storage.sqlite.UpdateURL"
+
+	tx, err := s.db.Begin()
 	if err != nil {
 		return fmt.Errorf("%s: %w", fn, err)
 	}
+	defer tx.Rollback()
+
+	// Record the history first: starting with a write takes the database lock
+	// up front, so no other update can slip in between reading the old
+	// destination and replacing it.
+	if upd.URL != nil {
+		_, err := tx.Exec(`
+			INSERT INTO url_history(url_id, old_url, new_url, changed_at, changed_by)
+			SELECT id, url, ?, ?, ? FROM url WHERE alias = ? AND user_id = ? AND url <> ?`,
+			*upd.URL, time.Now().UTC(), userId, alias, userId, *upd.URL,
+		)
+		if err != nil {
+			return fmt.Errorf("%s: %w", fn, err)
+		}
+	}
+
+	var expiresAt sql.NullTime
+	if upd.ExpiresAt != nil {
+		expiresAt = nullTime(*upd.ExpiresAt)
+	}
+	var redirectType sql.NullInt64
+	if upd.RedirectType != nil {
+		redirectType = nullInt64(int64(*upd.RedirectType))
+	}
 
-	_, err = query.Exec(alias)
+	res, err := tx.Exec(`
+		UPDATE url SET
+			url = COALESCE(?, url),
+			expires_at = CASE WHEN ? THEN ? ELSE expires_at END,
+			redirect_type = CASE WHEN ? THEN ? ELSE redirect_type END,
+			query_passthrough = COALESCE(?, query_passthrough),
+			path_passthrough = COALESCE(?, path_passthrough)
+		WHERE alias = ? AND user_id = ?`,
+		upd.URL, upd.ExpiresAt != nil, expiresAt, upd.RedirectType != nil, redirectType,
+		upd.QueryPassthrough, upd.PathPassthrough, alias, userId,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
 
+	affected, err := res.RowsAffected()
 	if err != nil {
 		return fmt.Errorf("%s: %w", fn, err)
 	}
+	if affected == 0 {
+		tx.Rollback()
+		return fmt.Errorf("%s: %w", fn, s.urlAccessError(alias))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
 
 	return nil
 }
 
+func (s *Storage) GetURLHistory(alias string, userId int64) ([]storage.URLChange, error) {
+	const fn = "storage.sqlite.GetURLHistory"
+
+	var urlId int64
+	var ownerId sql.NullInt64
+	err := s.db.QueryRow("SELECT id, user_id FROM url WHERE alias = ?", alias).Scan(&urlId, &ownerId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", fn, storage.ErrURLNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	if ownerId.Int64 != userId {
+		return nil, fmt.Errorf("%s: %w", fn, storage.ErrForbidden)
+	}
+
+	rows, err := s.db.Query(`
+		SELECT old_url, new_url, changed_at, COALESCE(changed_by, 0) FROM url_history
+		WHERE url_id = ? ORDER BY changed_at DESC, id DESC`,
+		urlId,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer rows.Close()
+
+	var history []storage.URLChange
+	for rows.Next() {
+		var c storage.URLChange
+		if err := rows.Scan(&c.OldURL, &c.NewURL, &c.ChangedAt, &c.ChangedBy); err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+		history = append(history, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return history, nil
+}
+
+func (s *Storage) DeleteExpiredURLs(before time.Time) (int64, error) {
+	const fn = "storage.sqlite.DeleteExpiredURLs"
+
+	query, err := s.db.Prepare("DELETE FROM url WHERE expires_at <= ?")
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	res, err := query.Exec(before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return deleted, nil
+}
+
 func (s *Storage) CreateUser(username string, password string) (int64, error) {
 	const fn = "storage.sqlite.CreateUser"
 
-	// TODO handle user already exists error
-
 	query, err := s.db.Prepare("INSERT INTO users(username, password) VALUES(?, ?)")
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", fn, err)
 	}
 
 	hashedPassword, err := security.HashPassword(password)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
 
 	res, err := query.Exec(username, hashedPassword)
 	if err != nil {
 		var sqliteErr sqlite3.Error
 		if errors.As(err, &sqliteErr) && errors.Is(sqliteErr.ExtendedCode, sqlite3.ErrConstraintUnique) {
-			return 0, fmt.Errorf("%s: %w", fn, storage.ErrURLExists)
+			return 0, fmt.Errorf("%s: %w", fn, storage.ErrUserExists)
 		}
 
 		return 0, fmt.Errorf("%s: %w", fn, err)
@@ -188,15 +542,15 @@ func (s *Storage) AuthenticateUser(username string, password string) (int64, err
 	return userId, nil
 }
 
-func (s *Storage) CreateSession(userId int64, token string) (int64, error) {
+func (s *Storage) CreateSession(userId int64, token string, expiresAt time.Time) (int64, error) {
 	const fn = "storage.sqlite.CreateSession"
 
-	query, err := s.db.Prepare("INSERT INTO sessions (user_id, token) VALUES (?, ?)")
+	query, err := s.db.Prepare("INSERT INTO sessions (user_id, token_hash, expires_at) VALUES (?, ?, ?)")
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", fn, err)
 	}
 
-	res, err := query.Exec(userId, token)
+	res, err := query.Exec(userId, security.HashToken(token), expiresAt.UTC())
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", fn, err)
 	}
@@ -208,3 +562,94 @@ func (s *Storage) CreateSession(userId int64, token string) (int64, error) {
 
 	return id, nil
 }
+
+func (s *Storage) GetSessionUserID(token string) (int64, error) {
+	const fn = "storage.sqlite.GetSessionUserID"
+
+	query, err := s.db.Prepare("SELECT user_id FROM sessions WHERE token_hash = ? AND expires_at > ?")
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	var userId int64
+	err = query.QueryRow(security.HashToken(token), time.Now().UTC()).Scan(&userId)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%s: %w", fn, storage.ErrSessionNotFound)
+		}
+
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return userId, nil
+}
+
+func (s *Storage) ExtendSession(token string, expiresAt time.Time) error {
+	const fn = "storage.sqlite.ExtendSession"
+
+	query, err := s.db.Prepare("UPDATE sessions SET expires_at = ? WHERE token_hash = ?")
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	_, err = query.Exec(expiresAt.UTC(), security.HashToken(token))
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteSession(token string) error {
+	const fn = "storage.sqlite.DeleteSession"
+
+	query, err := s.db.Prepare("DELETE FROM sessions WHERE token_hash = ?")
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	_, err = query.Exec(security.HashToken(token))
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteUserSessions(userId int64) error {
+	const fn = "storage.sqlite.DeleteUserSessions"
+
+	query, err := s.db.Prepare("DELETE FROM sessions WHERE user_id = ?")
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	_, err = query.Exec(userId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteExpiredSessions() (int64, error) {
+	const fn = "storage.sqlite.DeleteExpiredSessions"
+
+	query, err := s.db.Prepare("DELETE FROM sessions WHERE expires_at IS NULL OR expires_at <= ?")
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	res, err := query.Exec(time.Now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return deleted, nil
+}
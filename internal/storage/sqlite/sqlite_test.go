@@ -0,0 +1,130 @@
+package sqlite
+
+import (
+	"database/sql"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+	"time"
+	"url-shortener/internal/lib/security"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/storagetest"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Store {
+		s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() { s.Close() })
+
+		return s
+	})
+}
+
+func TestNew_HashesPlaintextSessionTokens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.db")
+
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+
+	_, err = db.Exec(`
+		CREATE TABLE users (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			username TEXT NOT NULL UNIQUE,
+			password TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE sessions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			token TEXT NOT NULL UNIQUE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		);
+		INSERT INTO users (id, username, password) VALUES (7, 'user', 'hash');
+	`)
+	require.NoError(t, err)
+
+	_, err = db.Exec(
+		"INSERT INTO sessions (user_id, token, expires_at) VALUES (?, ?, ?)",
+		7, "plaintext_token", time.Now().Add(time.Hour).UTC(),
+	)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	s, err := New(path)
+	require.NoError(t, err)
+
+	userId, err := s.GetSessionUserID("plaintext_token")
+	require.NoError(t, err)
+	require.Equal(t, int64(7), userId)
+
+	var stored string
+	require.NoError(t, s.db.QueryRow("SELECT token_hash FROM sessions").Scan(&stored))
+	require.Equal(t, security.HashToken("plaintext_token"), stored)
+
+	// Reopening an already migrated database is a no-op.
+	_, err = New(path)
+	require.NoError(t, err)
+}
+
+func TestSessions_StoreOnlyDigest(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	userId, err := s.CreateUser("user", "password")
+	require.NoError(t, err)
+
+	token, err := security.GenerateToken()
+	require.NoError(t, err)
+
+	_, err = s.CreateSession(userId, token, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	var leaked bool
+	require.NoError(t, s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM sessions WHERE token_hash = ?)", token).Scan(&leaked))
+	require.False(t, leaked)
+
+	got, err := s.GetSessionUserID(token)
+	require.NoError(t, err)
+	require.Equal(t, userId, got)
+
+	require.NoError(t, s.DeleteSession(token))
+
+	_, err = s.GetSessionUserID(token)
+	require.ErrorIs(t, err, storage.ErrSessionNotFound)
+}
+
+func TestListURLs_WithoutCreationTime(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+
+	userId, err := s.CreateUser("user", "password")
+	require.NoError(t, err)
+
+	for _, alias := range []string{"legacy-1", "legacy-2", "recent"} {
+		_, err := s.SaveURL("https://example.com/"+alias, alias, userId, storage.URLOptions{})
+		require.NoError(t, err)
+	}
+
+	// Links from before creation times were recorded have none.
+	_, err = s.db.Exec("UPDATE url SET created_at = NULL WHERE alias LIKE 'legacy-%'")
+	require.NoError(t, err)
+
+	var aliases []string
+	q := storage.URLQuery{Limit: 1}
+	for {
+		page, err := s.ListURLs(userId, q)
+		require.NoError(t, err)
+		if len(page) == 0 {
+			break
+		}
+		aliases = append(aliases, page[0].Alias)
+		cursor := page[0].Cursor()
+		q.After = &cursor
+	}
+
+	require.Equal(t, []string{"recent", "legacy-2", "legacy-1"}, aliases)
+}
@@ -1,10 +1,244 @@
 package storage
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	ErrURLNotFound  = errors.New("url not found")
 	ErrURLExists    = errors.New("url exists")
+	ErrURLExpired   = errors.New("url expired")
+	ErrURLExhausted = errors.New("url click limit reached")
+	ErrForbidden    = errors.New("forbidden")
 	ErrUserNotFound = errors.New("user not found")
 	ErrUserExists   = errors.New("user exists")
+
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// Store is implemented by every storage backend the server can run on.
+type Store interface {
+	URLStore
+	UserStore
+	SessionStore
+	ClickStore
+
+	Ping() error
+	// SchemaVersion reports the latest applied migration, 0 for backends
+	// without a schema.
+	SchemaVersion() (int64, error)
+	Close() error
+}
+
+// URLOptions holds the optional per-link settings. Zero values mean
+// "not set".
+type URLOptions struct {
+	// ID is a row id reserved with NextURLID; zero lets the store pick one.
+	ID        int64
+	ExpiresAt time.Time
+	MaxClicks int64
+	Tags      []string
+	// CreatedAt and Clicks carry over the history of imported links; a zero
+	// CreatedAt means now.
+	CreatedAt time.Time
+	Clicks    int64
+	// RedirectType is the HTTP status redirects answer with; zero means the
+	// configured default.
+	RedirectType int
+	Passthrough
+}
+
+// Query passthrough policies decide which value wins when an incoming query
+// parameter is already set on the destination. Append keeps both.
+const (
+	QueryKeep     = "keep"
+	QueryOverride = "override"
+	QueryAppend   = "append"
 )
+
+// Passthrough controls what of a redirect request carries over to the
+// destination: its query parameters, merged under QueryPassthrough unless
+// that is empty, and with PathPassthrough the path below the alias.
+type Passthrough struct {
+	QueryPassthrough string
+	PathPassthrough  bool
+}
+
+// URLUpdate lists the changes to a link. Nil fields are left as they are; a
+// zero ExpiresAt removes the expiry and a zero RedirectType restores the
+// default.
+type URLUpdate struct {
+	URL              *string
+	ExpiresAt        *time.Time
+	RedirectType     *int
+	QueryPassthrough *string
+	PathPassthrough  *bool
+}
+
+// URLTarget is where a redirect leads.
+type URLTarget struct {
+	URL string
+	// RedirectType is zero for links that use the configured default.
+	RedirectType int
+	ExpiresAt    time.Time
+	MaxClicks    int64
+	Passthrough
+}
+
+// URLChange records a change of a link's destination.
+type URLChange struct {
+	OldURL    string
+	NewURL    string
+	ChangedAt time.Time
+	ChangedBy int64
+}
+
+const (
+	SortCreated = "created"
+	SortClicks  = "clicks"
+)
+
+const (
+	ExpiryExpired   = "expired"
+	ExpiryExpiring  = "expiring"
+	ExpiryPermanent = "permanent"
+)
+
+// URLQuery selects a page of a user's links. Links come newest first by their
+// creation time, ties and links without one last, or with the most clicks
+// first for SortClicks. After continues a listing behind the
+// last link of the previous page.
+type URLQuery struct {
+	// Search matches a case-insensitive substring of the alias or destination.
+	Search string
+	Tag    string
+	// Expiry is one of the Expiry constants, or empty for any link.
+	Expiry string
+	Sort   string
+	After  *URLCursor
+	Limit  int
+}
+
+// URLCursor is the position of a link in a listing.
+type URLCursor struct {
+	ID        int64
+	Clicks    int64
+	CreatedAt time.Time
+}
+
+type URLInfo struct {
+	ID    int64
+	Alias string
+	URL   string
+	// CreatedAt is zero for links saved before creation times were recorded.
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	Clicks    int64
+	MaxClicks int64
+	Tags      []string
+	// RedirectType is zero for links that use the configured default.
+	RedirectType int
+	Passthrough
+}
+
+func (u URLInfo) Cursor() URLCursor {
+	return URLCursor{ID: u.ID, Clicks: u.Clicks, CreatedAt: u.CreatedAt}
+}
+
+// URLBatch saves many links in one transaction: nothing is stored until
+// Commit. A failed SaveURL affects only its own link, so the batch can go on.
+// Rollback after Commit does nothing, which lets it be deferred.
+type URLBatch interface {
+	NextURLID() (int64, error)
+	SaveURL(urlToSave string, alias string, userId int64, opts URLOptions) (int64, error)
+	Commit() error
+	Rollback() error
+}
+
+type URLStore interface {
+	// NextURLID reserves a row id for a link whose alias is derived from it.
+	// Reserved ids are never handed out twice, even if they end up unused.
+	NextURLID() (int64, error)
+	SaveURL(urlToSave string, alias string, userId int64, opts URLOptions) (int64, error)
+	BeginURLBatch() (URLBatch, error)
+	// GetURL returns ErrURLExpired for links past their expiry that have not
+	// been purged yet.
+	GetURL(alias string) (string, error)
+	// HitURL counts a redirect and returns the target in one atomic step. It
+	// returns ErrURLExpired or ErrURLExhausted instead once the link is dead,
+	// so concurrent redirects never exceed MaxClicks. A subPath redirect,
+	// one to a path below the alias, only finds links with PathPassthrough;
+	// for others it returns ErrURLNotFound without counting.
+	HitURL(alias string, subPath bool) (URLTarget, error)
+	// UpdateURL applies the update to a link of userId, recording a changed
+	// destination in the link's history. Expired links can be updated too, so
+	// that they can be revived before they are purged.
+	UpdateURL(alias string, userId int64, upd URLUpdate) error
+	// GetURLHistory returns the destination changes of a link, newest first.
+	GetURLHistory(alias string, userId int64) ([]URLChange, error)
+	// ListURLs pages through the links of userId using indexes on the owner,
+	// so its cost does not grow with the links of other users.
+	ListURLs(userId int64, q URLQuery) ([]URLInfo, error)
+	// WalkURLs calls visit for every link of userId, oldest first, reading
+	// them from storage a page at a time; no read is held open while visit
+	// runs, so a slow visit does not block writers. An error from visit stops
+	// the walk and is returned as is.
+	WalkURLs(userId int64, visit func(URLInfo) error) error
+	DeleteURL(alias string, userId int64) error
+	// ReplaceURL deletes a link of userId and saves a new one under its alias
+	// in one step, so the alias is never free in between. Nothing of the old
+	// link carries over, neither its settings nor its clicks or history.
+	ReplaceURL(urlToSave string, alias string, userId int64, opts URLOptions) (int64, error)
+	// DeleteExpiredURLs purges links that expired at or before the given time.
+	DeleteExpiredURLs(before time.Time) (int64, error)
+}
+
+type UserStore interface {
+	CreateUser(username string, password string) (int64, error)
+	// AuthenticateUser returns a zero user id when the password does not match.
+	AuthenticateUser(username string, password string) (int64, error)
+}
+
+// SessionStore keeps login sessions. Implementations must persist only a
+// digest of the token (see security.HashToken), never the token itself.
+type SessionStore interface {
+	CreateSession(userId int64, token string, expiresAt time.Time) (int64, error)
+	GetSessionUserID(token string) (int64, error)
+	ExtendSession(token string, expiresAt time.Time) error
+	DeleteSession(token string) error
+	DeleteUserSessions(userId int64) error
+	DeleteExpiredSessions() (int64, error)
+}
+
+// Click is a single recorded redirect. IPHash is a keyed digest of the
+// visitor address, never the address itself.
+type Click struct {
+	Alias     string
+	At        time.Time
+	Referrer  string
+	UserAgent string
+	IPHash    string
+	RequestID string
+}
+
+type URLStats struct {
+	TotalClicks    int64
+	UniqueVisitors int64
+	// Daily holds the days with at least one click, oldest first. Days are
+	// midnight UTC.
+	Daily []DailyClicks
+}
+
+type DailyClicks struct {
+	Date   time.Time
+	Clicks int64
+}
+
+type ClickStore interface {
+	// SaveClicks stores the clicks in one go. Clicks on aliases that no longer
+	// exist are dropped.
+	SaveClicks(clicks []Click) error
+	// GetURLStats returns ErrForbidden when the link belongs to someone else.
+	GetURLStats(alias string, userId int64) (URLStats, error)
+}
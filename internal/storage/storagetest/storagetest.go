@@ -0,0 +1,840 @@
+// Package storagetest holds the behaviour every storage.Store backend must
+// share. Backend packages run it from their own tests.
+package storagetest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+	"url-shortener/internal/storage"
+)
+
+func Run(t *testing.T, newStore func(t *testing.T) storage.Store) {
+	t.Run("Health", func(t *testing.T) {
+		s := newStore(t)
+
+		require.NoError(t, s.Ping())
+		_, err := s.SchemaVersion()
+		require.NoError(t, err)
+	})
+	t.Run("URLs", func(t *testing.T) {
+		testURLs(t, newStore(t))
+	})
+	t.Run("URLIDs", func(t *testing.T) {
+		testURLIDs(t, newStore(t))
+	})
+	t.Run("URLUpdates", func(t *testing.T) {
+		testURLUpdates(t, newStore(t))
+	})
+	t.Run("URLReplace", func(t *testing.T) {
+		testURLReplace(t, newStore(t))
+	})
+	t.Run("URLBatches", func(t *testing.T) {
+		testURLBatches(t, newStore(t))
+	})
+	t.Run("URLListing", func(t *testing.T) {
+		testURLListing(t, newStore(t))
+	})
+	t.Run("URLListingCreated", func(t *testing.T) {
+		testURLListingCreated(t, newStore(t))
+	})
+	t.Run("URLWalk", func(t *testing.T) {
+		testURLWalk(t, newStore(t))
+	})
+	t.Run("URLWalkWrites", func(t *testing.T) {
+		testURLWalkWrites(t, newStore(t))
+	})
+	t.Run("URLExpiry", func(t *testing.T) {
+		testURLExpiry(t, newStore(t))
+	})
+	t.Run("URLClickLimit", func(t *testing.T) {
+		testURLClickLimit(t, newStore(t))
+	})
+	t.Run("URLRedirectType", func(t *testing.T) {
+		testURLRedirectType(t, newStore(t))
+	})
+	t.Run("URLPassthrough", func(t *testing.T) {
+		testURLPassthrough(t, newStore(t))
+	})
+	t.Run("ConcurrentHits", func(t *testing.T) {
+		testConcurrentHits(t, newStore(t))
+	})
+	t.Run("Clicks", func(t *testing.T) {
+		testClicks(t, newStore(t))
+	})
+	t.Run("Users", func(t *testing.T) {
+		testUsers(t, newStore(t))
+	})
+	t.Run("Sessions", func(t *testing.T) {
+		testSessions(t, newStore(t))
+	})
+}
+
+func createUser(t *testing.T, s storage.Store, username string) int64 {
+	t.Helper()
+
+	userId, err := s.CreateUser(username, "password")
+	require.NoError(t, err)
+	require.NotZero(t, userId)
+
+	return userId
+}
+
+func testURLs(t *testing.T, s storage.Store) {
+	owner := createUser(t, s, "owner")
+	stranger := createUser(t, s, "stranger")
+
+	id, err := s.SaveURL("https://example.com", "alias", owner, storage.URLOptions{})
+	require.NoError(t, err)
+	require.NotZero(t, id)
+
+	_, err = s.SaveURL("https://example.org", "alias", stranger, storage.URLOptions{})
+	require.ErrorIs(t, err, storage.ErrURLExists)
+
+	got, err := s.GetURL("alias")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com", got)
+
+	_, err = s.GetURL("missing")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	require.ErrorIs(t, s.DeleteURL("alias", stranger), storage.ErrForbidden)
+	require.ErrorIs(t, s.DeleteURL("missing", owner), storage.ErrURLNotFound)
+	require.NoError(t, s.DeleteURL("alias", owner))
+
+	_, err = s.GetURL("alias")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func testURLIDs(t *testing.T, s storage.Store) {
+	owner := createUser(t, s, "owner")
+
+	reserved, err := s.NextURLID()
+	require.NoError(t, err)
+
+	// Plain inserts must not take the reserved id.
+	id, err := s.SaveURL("https://example.com/plain", "plain", owner, storage.URLOptions{})
+	require.NoError(t, err)
+	require.Greater(t, id, reserved)
+
+	id, err = s.SaveURL("https://example.com/reserved", "reserved", owner, storage.URLOptions{ID: reserved})
+	require.NoError(t, err)
+	require.Equal(t, reserved, id)
+
+	// Ids are not handed out again once their link is deleted.
+	require.NoError(t, s.DeleteURL("plain", owner))
+
+	next, err := s.NextURLID()
+	require.NoError(t, err)
+	require.Greater(t, next, id+1)
+}
+
+func testURLReplace(t *testing.T, s storage.Store) {
+	owner := createUser(t, s, "owner")
+	stranger := createUser(t, s, "stranger")
+
+	_, err := s.SaveURL("https://example.com/old", "alias", owner, storage.URLOptions{
+		ExpiresAt:    time.Now().Add(time.Hour),
+		MaxClicks:    5,
+		Tags:         []string{"old"},
+		RedirectType: 301,
+		Passthrough:  storage.Passthrough{PathPassthrough: true},
+	})
+	require.NoError(t, err)
+	_, err = s.HitURL("alias", false)
+	require.NoError(t, err)
+
+	_, err = s.ReplaceURL("https://example.com/new", "alias", stranger, storage.URLOptions{})
+	require.ErrorIs(t, err, storage.ErrForbidden)
+	_, err = s.ReplaceURL("https://example.com/new", "missing", owner, storage.URLOptions{})
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	id, err := s.ReplaceURL("https://example.com/new", "alias", owner, storage.URLOptions{Tags: []string{"new"}})
+	require.NoError(t, err)
+
+	// Nothing of the old link is left.
+	urls, err := s.ListURLs(owner, storage.URLQuery{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	require.Equal(t, id, urls[0].ID)
+	require.Equal(t, "https://example.com/new", urls[0].URL)
+	require.True(t, urls[0].ExpiresAt.IsZero())
+	require.Zero(t, urls[0].Clicks)
+	require.Zero(t, urls[0].MaxClicks)
+	require.Zero(t, urls[0].RedirectType)
+	require.Zero(t, urls[0].Passthrough)
+	require.Equal(t, []string{"new"}, urls[0].Tags)
+}
+
+func testURLUpdates(t *testing.T, s storage.Store) {
+	owner := createUser(t, s, "owner")
+	stranger := createUser(t, s, "stranger")
+
+	_, err := s.SaveURL("https://example.com/v1", "alias", owner, storage.URLOptions{
+		ExpiresAt: time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+
+	v2, v3 := "https://example.com/v2", "https://example.com/v3"
+	expiry := time.Now().Add(time.Hour)
+
+	require.ErrorIs(t, s.UpdateURL("missing", owner, storage.URLUpdate{URL: &v2}), storage.ErrURLNotFound)
+	require.ErrorIs(t, s.UpdateURL("alias", stranger, storage.URLUpdate{URL: &v2}), storage.ErrForbidden)
+
+	// Expired links can be revived.
+	require.NoError(t, s.UpdateURL("alias", owner, storage.URLUpdate{ExpiresAt: &expiry}))
+	got, err := s.GetURL("alias")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/v1", got)
+
+	require.NoError(t, s.UpdateURL("alias", owner, storage.URLUpdate{URL: &v2}))
+	require.NoError(t, s.UpdateURL("alias", owner, storage.URLUpdate{URL: &v2}))
+	require.NoError(t, s.UpdateURL("alias", owner, storage.URLUpdate{URL: &v3, ExpiresAt: &time.Time{}}))
+
+	got, err = s.GetURL("alias")
+	require.NoError(t, err)
+	require.Equal(t, v3, got)
+
+	deleted, err := s.DeleteExpiredURLs(time.Now().Add(48 * time.Hour))
+	require.NoError(t, err)
+	require.Zero(t, deleted, "the expiry should have been removed")
+
+	_, err = s.GetURLHistory("alias", stranger)
+	require.ErrorIs(t, err, storage.ErrForbidden)
+
+	// Unchanged destinations are not recorded.
+	history, err := s.GetURLHistory("alias", owner)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	require.Equal(t, "https://example.com/v2", history[0].OldURL)
+	require.Equal(t, v3, history[0].NewURL)
+	require.Equal(t, "https://example.com/v1", history[1].OldURL)
+	require.Equal(t, owner, history[1].ChangedBy)
+	require.WithinDuration(t, time.Now(), history[1].ChangedAt, time.Minute)
+}
+
+func testURLBatches(t *testing.T, s storage.Store) {
+	owner := createUser(t, s, "owner")
+
+	_, err := s.SaveURL("https://example.com/taken", "taken", owner, storage.URLOptions{})
+	require.NoError(t, err)
+
+	b, err := s.BeginURLBatch()
+	require.NoError(t, err)
+	defer b.Rollback()
+
+	id, err := b.NextURLID()
+	require.NoError(t, err)
+
+	saved, err := b.SaveURL("https://example.com/first", "first", owner, storage.URLOptions{ID: id, Tags: []string{"bulk"}})
+	require.NoError(t, err)
+	require.Equal(t, id, saved)
+
+	// A failed link leaves the batch usable.
+	_, err = b.SaveURL("https://example.com/other", "taken", owner, storage.URLOptions{})
+	require.ErrorIs(t, err, storage.ErrURLExists)
+
+	_, err = b.SaveURL("https://example.com/second", "second", owner, storage.URLOptions{})
+	require.NoError(t, err)
+
+	require.NoError(t, b.Commit())
+	require.NoError(t, b.Rollback())
+
+	for alias, want := range map[string]string{
+		"first":  "https://example.com/first",
+		"second": "https://example.com/second",
+		"taken":  "https://example.com/taken",
+	} {
+		got, err := s.GetURL(alias)
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	}
+
+	urls, err := s.ListURLs(owner, storage.URLQuery{Tag: "bulk", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	require.Equal(t, "first", urls[0].Alias)
+
+	b, err = s.BeginURLBatch()
+	require.NoError(t, err)
+
+	_, err = b.SaveURL("https://example.com/third", "third", owner, storage.URLOptions{})
+	require.NoError(t, err)
+	require.NoError(t, b.Rollback())
+
+	_, err = s.GetURL("third")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func testURLListing(t *testing.T, s storage.Store) {
+	owner := createUser(t, s, "owner")
+	stranger := createUser(t, s, "stranger")
+
+	save := func(alias string, userId int64, opts storage.URLOptions) {
+		t.Helper()
+		_, err := s.SaveURL("https://example.com/"+alias, alias, userId, opts)
+		require.NoError(t, err)
+	}
+
+	save("first", owner, storage.URLOptions{Tags: []string{"docs", "work"}})
+	save("second", owner, storage.URLOptions{ExpiresAt: time.Now().Add(time.Hour)})
+	save("third", owner, storage.URLOptions{ExpiresAt: time.Now().Add(-time.Minute), Tags: []string{"work"}})
+	save("fourth_100%", owner, storage.URLOptions{})
+	save("theirs", stranger, storage.URLOptions{Tags: []string{"work"}})
+
+	for i := 0; i < 2; i++ {
+		_, err := s.HitURL("second", false)
+		require.NoError(t, err)
+	}
+	_, err := s.HitURL("first", false)
+	require.NoError(t, err)
+
+	aliases := func(q storage.URLQuery) []string {
+		t.Helper()
+		if q.Limit == 0 {
+			q.Limit = 10
+		}
+		urls, err := s.ListURLs(owner, q)
+		require.NoError(t, err)
+
+		var res []string
+		for _, u := range urls {
+			res = append(res, u.Alias)
+		}
+		return res
+	}
+
+	require.Equal(t, []string{"fourth_100%", "third", "second", "first"}, aliases(storage.URLQuery{}))
+	require.Equal(t, []string{"second", "first", "fourth_100%", "third"}, aliases(storage.URLQuery{Sort: storage.SortClicks}))
+
+	require.Equal(t, []string{"third", "first"}, aliases(storage.URLQuery{Tag: "work"}))
+	require.Equal(t, []string{"third"}, aliases(storage.URLQuery{Expiry: storage.ExpiryExpired}))
+	require.Equal(t, []string{"second"}, aliases(storage.URLQuery{Expiry: storage.ExpiryExpiring}))
+	require.Equal(t, []string{"fourth_100%", "first"}, aliases(storage.URLQuery{Expiry: storage.ExpiryPermanent}))
+
+	// Search is case-insensitive and takes wildcards literally.
+	require.Equal(t, []string{"second"}, aliases(storage.URLQuery{Search: "SEC"}))
+	require.Equal(t, []string{"fourth_100%"}, aliases(storage.URLQuery{Search: "0%"}))
+	require.Empty(t, aliases(storage.URLQuery{Search: "f_r"}))
+
+	// Pages continue behind the cursor of the last link.
+	for _, sort := range []string{storage.SortCreated, storage.SortClicks} {
+		var all []string
+		q := storage.URLQuery{Sort: sort, Limit: 3}
+		for {
+			page, err := s.ListURLs(owner, q)
+			require.NoError(t, err)
+			for _, u := range page {
+				all = append(all, u.Alias)
+			}
+			if len(page) < q.Limit {
+				break
+			}
+			cursor := page[len(page)-1].Cursor()
+			q.After = &cursor
+		}
+		require.Equal(t, aliases(storage.URLQuery{Sort: sort}), all)
+	}
+
+	urls, err := s.ListURLs(owner, storage.URLQuery{Search: "first", Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	require.Equal(t, "https://example.com/first", urls[0].URL)
+	require.Equal(t, []string{"docs", "work"}, urls[0].Tags)
+	require.Equal(t, int64(1), urls[0].Clicks)
+	require.WithinDuration(t, time.Now(), urls[0].CreatedAt, time.Minute)
+}
+
+func testURLListingCreated(t *testing.T, s storage.Store) {
+	owner := createUser(t, s, "owner")
+
+	// Imported links keep their creation time but get new ids, so ids are out
+	// of creation order.
+	now := time.Now().Truncate(time.Second)
+	for _, link := range []struct {
+		alias     string
+		createdAt time.Time
+	}{
+		{"middle", now.Add(-time.Hour)},
+		{"oldest", now.Add(-2 * time.Hour)},
+		{"newest", now},
+		{"tied", now.Add(-time.Hour)},
+	} {
+		_, err := s.SaveURL("https://example.com/"+link.alias, link.alias, owner, storage.URLOptions{
+			CreatedAt: link.createdAt,
+		})
+		require.NoError(t, err)
+	}
+
+	want := []string{"newest", "tied", "middle", "oldest"}
+
+	urls, err := s.ListURLs(owner, storage.URLQuery{Limit: 10})
+	require.NoError(t, err)
+	var listed []string
+	for _, u := range urls {
+		listed = append(listed, u.Alias)
+	}
+	require.Equal(t, want, listed)
+
+	var paged []string
+	q := storage.URLQuery{Limit: 1}
+	for {
+		page, err := s.ListURLs(owner, q)
+		require.NoError(t, err)
+		if len(page) == 0 {
+			break
+		}
+		paged = append(paged, page[0].Alias)
+		cursor := page[0].Cursor()
+		q.After = &cursor
+	}
+	require.Equal(t, want, paged)
+}
+
+func testURLWalk(t *testing.T, s storage.Store) {
+	owner := createUser(t, s, "owner")
+	stranger := createUser(t, s, "stranger")
+
+	createdAt := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	_, err := s.SaveURL("https://example.com/imported", "imported", owner, storage.URLOptions{
+		CreatedAt: createdAt,
+		Clicks:    42,
+		MaxClicks: 100,
+		ExpiresAt: expiresAt,
+		Tags:      []string{"b", "a"},
+	})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://example.com/new", "new", owner, storage.URLOptions{})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://example.com/theirs", "theirs", stranger, storage.URLOptions{})
+	require.NoError(t, err)
+
+	var urls []storage.URLInfo
+	require.NoError(t, s.WalkURLs(owner, func(u storage.URLInfo) error {
+		urls = append(urls, u)
+		return nil
+	}))
+
+	require.Len(t, urls, 2)
+	require.Equal(t, "imported", urls[0].Alias)
+	require.Equal(t, "https://example.com/imported", urls[0].URL)
+	require.True(t, createdAt.Equal(urls[0].CreatedAt))
+	require.True(t, expiresAt.Equal(urls[0].ExpiresAt))
+	require.Equal(t, int64(42), urls[0].Clicks)
+	require.Equal(t, int64(100), urls[0].MaxClicks)
+	require.Equal(t, []string{"a", "b"}, urls[0].Tags)
+	require.Equal(t, "new", urls[1].Alias)
+	require.Empty(t, urls[1].Tags)
+	require.WithinDuration(t, time.Now(), urls[1].CreatedAt, time.Minute)
+
+	stop := errors.New("stop")
+	visited := 0
+	err = s.WalkURLs(owner, func(storage.URLInfo) error {
+		visited++
+		return stop
+	})
+	require.Equal(t, stop, err)
+	require.Equal(t, 1, visited)
+}
+
+// testURLWalkWrites walks enough links to span several pages and writes
+// while visiting them, as an export to a slow client does.
+func testURLWalkWrites(t *testing.T, s storage.Store) {
+	owner := createUser(t, s, "owner")
+	stranger := createUser(t, s, "stranger")
+
+	const links = 1200
+
+	batch, err := s.BeginURLBatch()
+	require.NoError(t, err)
+	for i := 0; i < links; i++ {
+		_, err := batch.SaveURL("https://example.com", fmt.Sprintf("link%d", i), owner, storage.URLOptions{})
+		require.NoError(t, err)
+	}
+	require.NoError(t, batch.Commit())
+
+	visited := 0
+	var lastID int64
+	require.NoError(t, s.WalkURLs(owner, func(u storage.URLInfo) error {
+		require.Greater(t, u.ID, lastID)
+		lastID = u.ID
+
+		if visited%100 == 0 {
+			_, err := s.HitURL(u.Alias, false)
+			require.NoError(t, err)
+			_, err = s.SaveURL("https://example.com", fmt.Sprintf("theirs%d", visited), stranger, storage.URLOptions{})
+			require.NoError(t, err)
+		}
+		visited++
+
+		return nil
+	}))
+	require.Equal(t, links, visited)
+}
+
+func testURLExpiry(t *testing.T, s storage.Store) {
+	owner := createUser(t, s, "owner")
+
+	now := time.Now()
+
+	_, err := s.SaveURL("https://example.com/live", "live", owner, storage.URLOptions{
+		ExpiresAt: now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://example.com/expired", "expired", owner, storage.URLOptions{
+		ExpiresAt: now.Add(-time.Minute),
+	})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://example.com/old", "old", owner, storage.URLOptions{
+		ExpiresAt: now.Add(-48 * time.Hour),
+	})
+	require.NoError(t, err)
+
+	got, err := s.GetURL("live")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/live", got)
+
+	_, err = s.GetURL("expired")
+	require.ErrorIs(t, err, storage.ErrURLExpired)
+
+	deleted, err := s.DeleteExpiredURLs(now.Add(-24 * time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, int64(1), deleted)
+
+	_, err = s.GetURL("old")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+	_, err = s.GetURL("expired")
+	require.ErrorIs(t, err, storage.ErrURLExpired)
+
+	deleted, err = s.DeleteExpiredURLs(now)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), deleted)
+
+	_, err = s.GetURL("live")
+	require.NoError(t, err)
+}
+
+func testURLClickLimit(t *testing.T, s storage.Store) {
+	owner := createUser(t, s, "owner")
+
+	_, err := s.SaveURL("https://example.com/once", "once", owner, storage.URLOptions{MaxClicks: 2})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://example.com/always", "always", owner, storage.URLOptions{})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://example.com/expired", "expired", owner, storage.URLOptions{
+		ExpiresAt: time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		got, err := s.HitURL("once", false)
+		require.NoError(t, err)
+		require.Equal(t, "https://example.com/once", got.URL)
+	}
+
+	_, err = s.HitURL("once", false)
+	require.ErrorIs(t, err, storage.ErrURLExhausted)
+
+	// Looking a link up does not count as a click.
+	got, err := s.GetURL("once")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/once", got)
+
+	for i := 0; i < 5; i++ {
+		_, err = s.HitURL("always", false)
+		require.NoError(t, err)
+	}
+
+	_, err = s.HitURL("expired", false)
+	require.ErrorIs(t, err, storage.ErrURLExpired)
+
+	_, err = s.HitURL("missing", false)
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func testURLRedirectType(t *testing.T, s storage.Store) {
+	owner := createUser(t, s, "owner")
+
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	_, err := s.SaveURL("https://example.com/moved", "moved", owner, storage.URLOptions{
+		RedirectType: 301,
+		ExpiresAt:    expiresAt,
+	})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://example.com/default", "default", owner, storage.URLOptions{})
+	require.NoError(t, err)
+
+	target, err := s.HitURL("moved", false)
+	require.NoError(t, err)
+	require.Equal(t, 301, target.RedirectType)
+	require.Zero(t, target.MaxClicks)
+	require.True(t, expiresAt.Equal(target.ExpiresAt))
+
+	target, err = s.HitURL("default", false)
+	require.NoError(t, err)
+	require.Zero(t, target.RedirectType)
+	require.True(t, target.ExpiresAt.IsZero())
+
+	redirectType := 307
+	require.NoError(t, s.UpdateURL("default", owner, storage.URLUpdate{RedirectType: &redirectType}))
+	target, err = s.HitURL("default", false)
+	require.NoError(t, err)
+	require.Equal(t, 307, target.RedirectType)
+
+	// Other updates leave the redirect type alone; zero restores the default.
+	newURL := "https://example.com/other"
+	require.NoError(t, s.UpdateURL("moved", owner, storage.URLUpdate{URL: &newURL}))
+	target, err = s.HitURL("moved", false)
+	require.NoError(t, err)
+	require.Equal(t, 301, target.RedirectType)
+
+	redirectType = 0
+	require.NoError(t, s.UpdateURL("moved", owner, storage.URLUpdate{RedirectType: &redirectType}))
+	target, err = s.HitURL("moved", false)
+	require.NoError(t, err)
+	require.Zero(t, target.RedirectType)
+
+	// Listings and exports carry the redirect type too.
+	want := map[string]int{"moved": 0, "default": 307}
+
+	urls, err := s.ListURLs(owner, storage.URLQuery{Limit: 10})
+	require.NoError(t, err)
+	listed := make(map[string]int)
+	for _, u := range urls {
+		listed[u.Alias] = u.RedirectType
+	}
+	require.Equal(t, want, listed)
+
+	walked := make(map[string]int)
+	require.NoError(t, s.WalkURLs(owner, func(u storage.URLInfo) error {
+		walked[u.Alias] = u.RedirectType
+		return nil
+	}))
+	require.Equal(t, want, walked)
+}
+
+func testURLPassthrough(t *testing.T, s storage.Store) {
+	owner := createUser(t, s, "owner")
+
+	docs := storage.Passthrough{QueryPassthrough: storage.QueryOverride, PathPassthrough: true}
+
+	_, err := s.SaveURL("https://example.com/docs", "docs", owner, storage.URLOptions{Passthrough: docs})
+	require.NoError(t, err)
+	_, err = s.SaveURL("https://example.com/plain", "plain", owner, storage.URLOptions{})
+	require.NoError(t, err)
+
+	target, err := s.HitURL("docs", false)
+	require.NoError(t, err)
+	require.Equal(t, docs, target.Passthrough)
+
+	target, err = s.HitURL("plain", false)
+	require.NoError(t, err)
+	require.Equal(t, storage.Passthrough{}, target.Passthrough)
+
+	// Sub-paths only reach links that pass them through, and others are not
+	// charged a click for them.
+	_, err = s.SaveURL("https://example.com/once", "once", owner, storage.URLOptions{MaxClicks: 1})
+	require.NoError(t, err)
+	_, err = s.HitURL("once", true)
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+	target, err = s.HitURL("once", false)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), target.MaxClicks)
+	_, err = s.HitURL("once", true)
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+	_, err = s.HitURL("once", false)
+	require.ErrorIs(t, err, storage.ErrURLExhausted)
+
+	target, err = s.HitURL("docs", true)
+	require.NoError(t, err)
+	require.Equal(t, docs, target.Passthrough)
+
+	// Each option can be changed on its own.
+	query, path := storage.QueryAppend, false
+	require.NoError(t, s.UpdateURL("docs", owner, storage.URLUpdate{QueryPassthrough: &query}))
+	target, err = s.HitURL("docs", false)
+	require.NoError(t, err)
+	require.Equal(t, storage.Passthrough{QueryPassthrough: storage.QueryAppend, PathPassthrough: true}, target.Passthrough)
+
+	require.NoError(t, s.UpdateURL("docs", owner, storage.URLUpdate{PathPassthrough: &path}))
+	target, err = s.HitURL("docs", false)
+	require.NoError(t, err)
+	require.Equal(t, storage.Passthrough{QueryPassthrough: storage.QueryAppend}, target.Passthrough)
+
+	// Listings and exports carry the options too.
+	want := map[string]storage.Passthrough{
+		"docs":  {QueryPassthrough: storage.QueryAppend},
+		"plain": {},
+		"once":  {},
+	}
+
+	urls, err := s.ListURLs(owner, storage.URLQuery{Limit: 10})
+	require.NoError(t, err)
+	listed := make(map[string]storage.Passthrough)
+	for _, u := range urls {
+		listed[u.Alias] = u.Passthrough
+	}
+	require.Equal(t, want, listed)
+
+	walked := make(map[string]storage.Passthrough)
+	require.NoError(t, s.WalkURLs(owner, func(u storage.URLInfo) error {
+		walked[u.Alias] = u.Passthrough
+		return nil
+	}))
+	require.Equal(t, want, walked)
+}
+
+func testConcurrentHits(t *testing.T, s storage.Store) {
+	owner := createUser(t, s, "owner")
+
+	const maxClicks = 5
+	const workers = 40
+
+	_, err := s.SaveURL("https://example.com", "alias", owner, storage.URLOptions{MaxClicks: maxClicks})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	hits := 0
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := s.HitURL("alias", false)
+			if err == nil {
+				mu.Lock()
+				hits++
+				mu.Unlock()
+				return
+			}
+			require.ErrorIs(t, err, storage.ErrURLExhausted)
+		}()
+	}
+
+	wg.Wait()
+
+	require.Equal(t, maxClicks, hits)
+}
+
+func testClicks(t *testing.T, s storage.Store) {
+	owner := createUser(t, s, "owner")
+	stranger := createUser(t, s, "stranger")
+
+	_, err := s.SaveURL("https://example.com", "alias", owner, storage.URLOptions{})
+	require.NoError(t, err)
+
+	stats, err := s.GetURLStats("alias", owner)
+	require.NoError(t, err)
+	require.Zero(t, stats.TotalClicks)
+	require.Empty(t, stats.Daily)
+
+	yesterday := time.Date(2024, 3, 9, 23, 59, 0, 0, time.UTC)
+	today := time.Date(2024, 3, 10, 8, 0, 0, 0, time.UTC)
+
+	err = s.SaveClicks([]storage.Click{
+		{Alias: "alias", At: yesterday, IPHash: "a", Referrer: "https://ref.example", RequestID: "r1"},
+		{Alias: "alias", At: today, IPHash: "a", UserAgent: "curl"},
+		{Alias: "alias", At: today.Add(time.Hour), IPHash: "b"},
+		{Alias: "missing", At: today, IPHash: "c"},
+	})
+	require.NoError(t, err)
+
+	stats, err = s.GetURLStats("alias", owner)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), stats.TotalClicks)
+	require.Equal(t, int64(2), stats.UniqueVisitors)
+	require.Len(t, stats.Daily, 2)
+	require.True(t, stats.Daily[0].Date.Equal(time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC)))
+	require.Equal(t, int64(1), stats.Daily[0].Clicks)
+	require.True(t, stats.Daily[1].Date.Equal(time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)))
+	require.Equal(t, int64(2), stats.Daily[1].Clicks)
+
+	_, err = s.GetURLStats("alias", stranger)
+	require.ErrorIs(t, err, storage.ErrForbidden)
+	_, err = s.GetURLStats("missing", owner)
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	// Clicks go away with their link and do not leak into a new one.
+	require.NoError(t, s.DeleteURL("alias", owner))
+	_, err = s.SaveURL("https://example.org", "alias", owner, storage.URLOptions{})
+	require.NoError(t, err)
+
+	stats, err = s.GetURLStats("alias", owner)
+	require.NoError(t, err)
+	require.Zero(t, stats.TotalClicks)
+}
+
+func testUsers(t *testing.T, s storage.Store) {
+	userId := createUser(t, s, "user")
+
+	_, err := s.CreateUser("user", "other")
+	require.ErrorIs(t, err, storage.ErrUserExists)
+
+	got, err := s.AuthenticateUser("user", "password")
+	require.NoError(t, err)
+	require.Equal(t, userId, got)
+
+	got, err = s.AuthenticateUser("user", "wrong")
+	require.NoError(t, err)
+	require.Zero(t, got)
+
+	_, err = s.AuthenticateUser("missing", "password")
+	require.ErrorIs(t, err, storage.ErrUserNotFound)
+}
+
+func testSessions(t *testing.T, s storage.Store) {
+	userId := createUser(t, s, "user")
+	otherId := createUser(t, s, "other")
+
+	_, err := s.CreateSession(userId, "first", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	_, err = s.CreateSession(userId, "second", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	_, err = s.CreateSession(otherId, "other", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	_, err = s.CreateSession(userId, "expired", time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+
+	got, err := s.GetSessionUserID("first")
+	require.NoError(t, err)
+	require.Equal(t, userId, got)
+
+	_, err = s.GetSessionUserID("unknown")
+	require.ErrorIs(t, err, storage.ErrSessionNotFound)
+
+	_, err = s.GetSessionUserID("expired")
+	require.ErrorIs(t, err, storage.ErrSessionNotFound)
+
+	require.NoError(t, s.ExtendSession("expired", time.Now().Add(time.Hour)))
+	got, err = s.GetSessionUserID("expired")
+	require.NoError(t, err)
+	require.Equal(t, userId, got)
+
+	require.NoError(t, s.ExtendSession("expired", time.Now().Add(-time.Minute)))
+	deleted, err := s.DeleteExpiredSessions()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), deleted)
+
+	require.NoError(t, s.DeleteSession("first"))
+	_, err = s.GetSessionUserID("first")
+	require.ErrorIs(t, err, storage.ErrSessionNotFound)
+
+	_, err = s.GetSessionUserID("second")
+	require.NoError(t, err)
+
+	require.NoError(t, s.DeleteUserSessions(userId))
+	_, err = s.GetSessionUserID("second")
+	require.ErrorIs(t, err, storage.ErrSessionNotFound)
+
+	got, err = s.GetSessionUserID("other")
+	require.NoError(t, err)
+	require.Equal(t, otherId, got)
+}
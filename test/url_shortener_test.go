@@ -1,20 +1,56 @@
 package test
 
 import (
+	"encoding/json"
 	"github.com/brianvoe/gofakeit/v6"
 	"github.com/gavv/httpexpect/v2"
 	"github.com/stretchr/testify/require"
 	"net/http"
 	"net/url"
 	"testing"
+	"time"
+	"url-shortener/internal/api/handlers/bulk"
+	"url-shortener/internal/api/handlers/login"
+	"url-shortener/internal/api/handlers/register"
 	"url-shortener/internal/api/handlers/save"
+	"url-shortener/internal/api/handlers/stats"
+	"url-shortener/internal/api/handlers/update"
+	"url-shortener/internal/api/response"
 	"url-shortener/internal/lib/random"
 )
 
 const (
-	host = "localhost:8080"
+	host      = "localhost:8080"
+	adminHost = "localhost:9090"
 )
 
+func authenticate(e *httpexpect.Expect) string {
+	username := gofakeit.Username() + random.String(6)
+	password := gofakeit.Password(true, true, true, false, false, 12)
+
+	e.POST("/register").
+		WithJSON(register.Request{
+			Username: username,
+			Password: password,
+		}).
+		Expect().
+		Status(http.StatusOK)
+
+	return logIn(e, username, password)
+}
+
+func logIn(e *httpexpect.Expect, username string, password string) string {
+	return e.POST("/login").
+		WithJSON(login.Request{
+			Username: username,
+			Password: password,
+		}).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().
+		Value("token").String().NotEmpty().Raw()
+}
+
 func TestURLShortener_Save(t *testing.T) {
 	u := url.URL{
 		Scheme: "http",
@@ -22,7 +58,10 @@ func TestURLShortener_Save(t *testing.T) {
 	}
 	e := httpexpect.Default(t, u.String())
 
+	token := authenticate(e)
+
 	e.POST("/save").
+		WithHeader("Authorization", "Bearer "+token).
 		WithJSON(save.Request{
 			URL:   gofakeit.URL(),
 			Alias: random.String(6),
@@ -33,28 +72,84 @@ func TestURLShortener_Save(t *testing.T) {
 		ContainsKey("alias")
 }
 
+func TestURLShortener_SaveAliasPolicy(t *testing.T) {
+	u := url.URL{
+		Scheme: "http",
+		Host:   host,
+	}
+	e := httpexpect.Default(t, u.String())
+
+	token := authenticate(e)
+
+	for alias, rule := range map[string]string{
+		"login":                      "reserved",
+		"readyz":                     "reserved",
+		"admin":                      "reserved",
+		"a/b":                        "charset",
+		"ab":                         "min",
+		"zürich-" + random.String(4): "charset",
+	} {
+		e.POST("/save").
+			WithHeader("Authorization", "Bearer "+token).
+			WithJSON(save.Request{
+				URL:   gofakeit.URL(),
+				Alias: alias,
+			}).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON().Object().
+			Value("errors").Array().Value(0).Object().
+			HasValue("field", "alias").
+			HasValue("rule", rule)
+	}
+}
+
+func TestURLShortener_SaveUnauthorized(t *testing.T) {
+	u := url.URL{
+		Scheme: "http",
+		Host:   host,
+	}
+	e := httpexpect.Default(t, u.String())
+
+	e.POST("/save").
+		WithJSON(save.Request{
+			URL:   gofakeit.URL(),
+			Alias: random.String(6),
+		}).
+		Expect().
+		Status(http.StatusUnauthorized)
+
+	e.DELETE("/" + random.String(6)).
+		Expect().
+		Status(http.StatusUnauthorized)
+}
+
 func TestURLShortener_SaveRedirect(t *testing.T) {
 	tests := []struct {
-		name  string
-		url   string
-		alias string
-		error string
+		name   string
+		url    string
+		alias  string
+		status int
+		error  string
 	}{
 		{
-			name:  "Valid URL",
-			url:   gofakeit.URL(),
-			alias: gofakeit.Word() + gofakeit.Word(),
+			name:   "Valid URL",
+			url:    gofakeit.URL(),
+			alias:  gofakeit.Word() + gofakeit.Word(),
+			status: http.StatusOK,
 		},
 		{
-			name:  "Invalid URL",
-			url:   "invalid_url",
-			alias: gofakeit.Word(),
-			error: "failed to validate request URL",
+			name:   "Invalid URL",
+			url:    "invalid_url",
+			alias:  gofakeit.Word(),
+			status: http.StatusBadRequest,
+			error:  "validation failed",
 		},
 		{
-			name:  "Empty Alias",
-			url:   gofakeit.URL(),
-			alias: "",
+			name:   "Empty Alias",
+			url:    gofakeit.URL(),
+			alias:  "",
+			status: http.StatusOK,
 		},
 	}
 
@@ -67,18 +162,23 @@ func TestURLShortener_SaveRedirect(t *testing.T) {
 
 			e := httpexpect.Default(t, u.String())
 
+			token := authenticate(e)
+
 			// Save
 			resp := e.POST("/save").
+				WithHeader("Authorization", "Bearer "+token).
 				WithJSON(save.Request{
 					URL:   tc.url,
 					Alias: tc.alias,
 				}).
-				Expect().Status(http.StatusOK).
+				Expect().Status(tc.status).
 				JSON().Object()
 
 			if tc.error != "" {
 				resp.NotContainsKey("alias")
 				resp.Value("error").String().IsEqual(tc.error)
+				resp.Value("code").String().IsEqual(response.CodeValidationFailed)
+				resp.Value("errors").Array().Value(0).Object().Value("field").String().IsEqual("url")
 				return
 			}
 
@@ -103,13 +203,674 @@ func TestURLShortener_SaveRedirect(t *testing.T) {
 				return http.ErrUseLastResponse
 			}
 
-			response, err := client.Get(u.String())
+			res, err := client.Get(u.String())
 			if err != nil {
 				t.Fail()
 			}
 
-			redirectUrl := response.Header.Get("Location")
+			redirectUrl := res.Header.Get("Location")
 			require.Equal(t, tc.url, redirectUrl)
 		})
 	}
 }
+
+func TestURLShortener_DeleteOwnership(t *testing.T) {
+	u := url.URL{
+		Scheme: "http",
+		Host:   host,
+	}
+	e := httpexpect.Default(t, u.String())
+
+	owner := authenticate(e)
+	stranger := authenticate(e)
+
+	alias := random.String(10)
+
+	e.POST("/save").
+		WithHeader("Authorization", "Bearer "+owner).
+		WithJSON(save.Request{
+			URL:   gofakeit.URL(),
+			Alias: alias,
+		}).
+		Expect().
+		Status(http.StatusOK)
+
+	e.DELETE("/"+alias).
+		WithHeader("Authorization", "Bearer "+stranger).
+		Expect().
+		Status(http.StatusForbidden)
+
+	e.DELETE("/"+alias).
+		WithHeader("Authorization", "Bearer "+owner).
+		Expect().
+		Status(http.StatusOK)
+
+	e.DELETE("/"+alias).
+		WithHeader("Authorization", "Bearer "+owner).
+		Expect().
+		Status(http.StatusNotFound)
+}
+
+func TestURLShortener_Update(t *testing.T) {
+	u := url.URL{
+		Scheme: "http",
+		Host:   host,
+	}
+	e := httpexpect.Default(t, u.String())
+
+	owner := authenticate(e)
+	stranger := authenticate(e)
+
+	alias := random.String(10)
+	oldURL, newURL := gofakeit.URL(), gofakeit.URL()
+
+	e.POST("/save").
+		WithHeader("Authorization", "Bearer "+owner).
+		WithJSON(save.Request{
+			URL:   oldURL,
+			Alias: alias,
+		}).
+		Expect().
+		Status(http.StatusOK)
+
+	e.PATCH("/"+alias).
+		WithHeader("Authorization", "Bearer "+stranger).
+		WithJSON(update.Request{URL: newURL}).
+		Expect().
+		Status(http.StatusForbidden)
+
+	e.PATCH("/"+alias).
+		WithHeader("Authorization", "Bearer "+owner).
+		WithJSON(update.Request{URL: newURL}).
+		Expect().
+		Status(http.StatusOK)
+
+	e.GET("/" + alias).
+		WithRedirectPolicy(httpexpect.DontFollowRedirects).
+		Expect().
+		Status(http.StatusFound).
+		Header("Location").IsEqual(newURL)
+
+	e.GET("/"+alias+"/history").
+		WithHeader("Authorization", "Bearer "+stranger).
+		Expect().
+		Status(http.StatusForbidden)
+
+	changes := e.GET("/"+alias+"/history").
+		WithHeader("Authorization", "Bearer "+owner).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().
+		Value("changes").Array()
+
+	changes.Length().IsEqual(1)
+
+	change := changes.Value(0).Object()
+	change.Value("old_url").String().IsEqual(oldURL)
+	change.Value("new_url").String().IsEqual(newURL)
+}
+
+func TestURLShortener_RedirectType(t *testing.T) {
+	u := url.URL{
+		Scheme: "http",
+		Host:   host,
+	}
+	e := httpexpect.Default(t, u.String())
+
+	token := authenticate(e)
+
+	alias := random.String(10)
+	target := gofakeit.URL()
+
+	e.POST("/save").
+		WithHeader("Authorization", "Bearer "+token).
+		WithJSON(save.Request{
+			URL:          target,
+			Alias:        alias,
+			RedirectType: http.StatusMovedPermanently,
+		}).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().
+		Value("redirect_type").Number().IsEqual(http.StatusMovedPermanently)
+
+	res := e.GET("/" + alias).
+		WithRedirectPolicy(httpexpect.DontFollowRedirects).
+		Expect().
+		Status(http.StatusMovedPermanently)
+	res.Header("Location").IsEqual(target)
+	res.Header("Cache-Control").HasPrefix("public, max-age=")
+
+	redirectType := http.StatusTemporaryRedirect
+	e.PATCH("/"+alias).
+		WithHeader("Authorization", "Bearer "+token).
+		WithJSON(update.Request{RedirectType: &redirectType}).
+		Expect().
+		Status(http.StatusOK)
+
+	res = e.GET("/" + alias).
+		WithRedirectPolicy(httpexpect.DontFollowRedirects).
+		Expect().
+		Status(http.StatusTemporaryRedirect)
+	res.Header("Location").IsEqual(target)
+	res.Header("Cache-Control").IsEqual("no-store")
+}
+
+func TestURLShortener_FormatExtension(t *testing.T) {
+	u := url.URL{
+		Scheme: "http",
+		Host:   host,
+	}
+	e := httpexpect.Default(t, u.String())
+
+	token := authenticate(e)
+
+	alias := random.String(10)
+	target := gofakeit.URL()
+
+	e.POST("/save").
+		WithHeader("Authorization", "Bearer "+token).
+		WithJSON(save.Request{URL: target, Alias: alias}).
+		Expect().
+		Status(http.StatusOK)
+
+	// A format extension is stripped before routing.
+	e.GET("/" + alias + ".json").
+		WithRedirectPolicy(httpexpect.DontFollowRedirects).
+		Expect().
+		Status(http.StatusFound).
+		Header("Location").IsEqual(target)
+}
+
+func TestURLShortener_Passthrough(t *testing.T) {
+	u := url.URL{
+		Scheme: "http",
+		Host:   host,
+	}
+	e := httpexpect.Default(t, u.String())
+
+	token := authenticate(e)
+
+	alias := random.String(10)
+
+	e.POST("/save").
+		WithHeader("Authorization", "Bearer "+token).
+		WithJSON(save.Request{
+			URL:              "https://example.com/docs?lang=en",
+			Alias:            alias,
+			QueryPassthrough: "keep",
+			PathPassthrough:  true,
+		}).
+		Expect().
+		Status(http.StatusOK)
+
+	e.GET("/"+alias+"/guide/intro").
+		WithQuery("lang", "de").
+		WithQuery("utm_source", "test").
+		WithRedirectPolicy(httpexpect.DontFollowRedirects).
+		Expect().
+		Status(http.StatusFound).
+		Header("Location").IsEqual("https://example.com/docs/guide/intro?lang=en&utm_source=test")
+
+	// Links without passthrough do not answer below their alias, and are not
+	// charged a click for it.
+	once := random.String(10)
+	e.POST("/save").
+		WithHeader("Authorization", "Bearer "+token).
+		WithJSON(save.Request{URL: "https://example.com/once", Alias: once, MaxClicks: 1}).
+		Expect().
+		Status(http.StatusOK)
+	e.GET("/" + once + "/anything").
+		WithRedirectPolicy(httpexpect.DontFollowRedirects).
+		Expect().
+		Status(http.StatusNotFound)
+	e.GET("/" + once).
+		WithRedirectPolicy(httpexpect.DontFollowRedirects).
+		Expect().
+		Status(http.StatusFound)
+
+	// Routes below an alias take precedence over passthrough.
+	e.GET("/" + alias + "/stats").
+		Expect().
+		Status(http.StatusUnauthorized)
+
+	off := "off"
+	e.PATCH("/"+alias).
+		WithHeader("Authorization", "Bearer "+token).
+		WithJSON(update.Request{QueryPassthrough: &off}).
+		Expect().
+		Status(http.StatusOK)
+
+	e.GET("/"+alias+"/guide").
+		WithQuery("utm_source", "test").
+		WithRedirectPolicy(httpexpect.DontFollowRedirects).
+		Expect().
+		Status(http.StatusFound).
+		Header("Location").IsEqual("https://example.com/docs/guide?lang=en")
+}
+
+func TestURLShortener_List(t *testing.T) {
+	u := url.URL{
+		Scheme: "http",
+		Host:   host,
+	}
+	e := httpexpect.Default(t, u.String())
+
+	owner := authenticate(e)
+	stranger := authenticate(e)
+
+	tag := random.String(8)
+	aliases := []string{random.String(10), random.String(10), random.String(10)}
+	for _, alias := range aliases {
+		e.POST("/save").
+			WithHeader("Authorization", "Bearer "+owner).
+			WithJSON(save.Request{
+				URL:   gofakeit.URL(),
+				Alias: alias,
+				Tags:  []string{tag},
+			}).
+			Expect().
+			Status(http.StatusOK)
+	}
+
+	e.GET("/links").
+		Expect().
+		Status(http.StatusUnauthorized)
+
+	e.GET("/links").
+		WithHeader("Authorization", "Bearer "+stranger).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().
+		Value("links").Array().IsEmpty()
+
+	page := e.GET("/links").
+		WithHeader("Authorization", "Bearer "+owner).
+		WithQuery("tag", tag).
+		WithQuery("limit", 2).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+
+	links := page.Value("links").Array()
+	links.Length().IsEqual(2)
+	links.Value(0).Object().Value("alias").String().IsEqual(aliases[2])
+	links.Value(0).Object().Value("tags").Array().IsEqual([]string{tag})
+	links.Value(1).Object().Value("alias").String().IsEqual(aliases[1])
+
+	links = e.GET("/links").
+		WithHeader("Authorization", "Bearer "+owner).
+		WithQuery("tag", tag).
+		WithQuery("limit", 2).
+		WithQuery("cursor", page.Value("next_cursor").String().Raw()).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().
+		NotContainsKey("next_cursor").
+		Value("links").Array()
+
+	links.Length().IsEqual(1)
+	links.Value(0).Object().Value("alias").String().IsEqual(aliases[0])
+
+	e.GET("/links").
+		WithHeader("Authorization", "Bearer "+owner).
+		WithQuery("q", aliases[1]).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().
+		Value("links").Array().Length().IsEqual(1)
+
+	e.GET("/links").
+		WithHeader("Authorization", "Bearer "+owner).
+		WithQuery("sort", "alias").
+		Expect().
+		Status(http.StatusBadRequest)
+}
+
+func TestURLShortener_Bulk(t *testing.T) {
+	u := url.URL{
+		Scheme: "http",
+		Host:   host,
+	}
+	e := httpexpect.Default(t, u.String())
+
+	token := authenticate(e)
+
+	alias := random.String(10)
+	target := gofakeit.URL()
+
+	items := e.POST("/links/bulk").
+		WithHeader("Authorization", "Bearer "+token).
+		WithJSON(bulk.Request{
+			Items: []save.Request{
+				{URL: target, Alias: alias},
+				{URL: gofakeit.URL()},
+				{URL: gofakeit.URL(), Alias: alias},
+			},
+		}).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().
+		HasValue("saved", 2).
+		Value("items").Array()
+
+	items.Value(0).Object().Value("alias").String().IsEqual(alias)
+	items.Value(1).Object().Value("alias").String().NotEmpty()
+	items.Value(2).Object().Value("code").String().IsEqual("ALIAS_TAKEN")
+
+	e.GET("/" + alias).
+		WithRedirectPolicy(httpexpect.DontFollowRedirects).
+		Expect().
+		Status(http.StatusFound).
+		Header("Location").IsEqual(target)
+
+	other := random.String(10)
+
+	e.POST("/links/bulk").
+		WithHeader("Authorization", "Bearer "+token).
+		WithJSON(bulk.Request{
+			Atomic: true,
+			Items: []save.Request{
+				{URL: gofakeit.URL(), Alias: other},
+				{URL: gofakeit.URL(), Alias: alias},
+			},
+		}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).
+		JSON().Object().
+		HasValue("code", "BULK_FAILED")
+
+	e.GET("/" + other).
+		WithRedirectPolicy(httpexpect.DontFollowRedirects).
+		Expect().
+		Status(http.StatusNotFound)
+}
+
+func TestURLShortener_ExportImport(t *testing.T) {
+	u := url.URL{
+		Scheme: "http",
+		Host:   host,
+	}
+	e := httpexpect.Default(t, u.String())
+
+	owner := authenticate(e)
+	other := authenticate(e)
+
+	alias := random.String(10)
+	target := gofakeit.URL()
+
+	e.POST("/save").
+		WithHeader("Authorization", "Bearer "+owner).
+		WithJSON(save.Request{
+			URL:   target,
+			Alias: alias,
+			Tags:  []string{"exported"},
+		}).
+		Expect().
+		Status(http.StatusOK)
+
+	export := e.GET("/links/export").
+		WithHeader("Authorization", "Bearer "+owner).
+		WithQuery("format", "jsonl").
+		Expect().
+		Status(http.StatusOK)
+
+	export.Header("Content-Type").IsEqual("application/x-ndjson")
+
+	var rec map[string]any
+	require.NoError(t, json.Unmarshal([]byte(export.Body().Raw()), &rec))
+	require.Equal(t, alias, rec["alias"])
+	require.Equal(t, target, rec["url"])
+	require.Equal(t, []any{"exported"}, rec["tags"])
+
+	csv := e.GET("/links/export").
+		WithHeader("Authorization", "Bearer "+owner).
+		Expect().
+		Status(http.StatusOK).
+		Body().Raw()
+
+	// Importing the export elsewhere keeps the aliases of free links and
+	// renames taken ones.
+	fresh := random.String(10)
+	csv += fresh + "," + gofakeit.URL() + ",,,0,0,\n"
+
+	resp := e.POST("/links/import").
+		WithHeader("Authorization", "Bearer "+other).
+		WithQuery("conflict", "rename").
+		WithText(csv).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+
+	resp.HasValue("saved", 2)
+	renamed := resp.Value("renamed").Array()
+	renamed.Length().IsEqual(1)
+	renamed.Value(0).Object().HasValue("alias", alias)
+
+	e.GET("/" + renamed.Value(0).Object().Value("new_alias").String().Raw()).
+		WithRedirectPolicy(httpexpect.DontFollowRedirects).
+		Expect().
+		Status(http.StatusFound).
+		Header("Location").IsEqual(target)
+
+	e.GET("/" + fresh).
+		WithRedirectPolicy(httpexpect.DontFollowRedirects).
+		Expect().
+		Status(http.StatusFound)
+}
+
+func TestURLShortener_Logout(t *testing.T) {
+	u := url.URL{
+		Scheme: "http",
+		Host:   host,
+	}
+	e := httpexpect.Default(t, u.String())
+
+	username := gofakeit.Username() + random.String(6)
+	password := gofakeit.Password(true, true, true, false, false, 12)
+
+	e.POST("/register").
+		WithJSON(register.Request{
+			Username: username,
+			Password: password,
+		}).
+		Expect().
+		Status(http.StatusOK)
+
+	first := logIn(e, username, password)
+	second := logIn(e, username, password)
+	third := logIn(e, username, password)
+
+	// Logout revokes only the current token.
+	e.POST("/logout").
+		WithHeader("Authorization", "Bearer "+first).
+		Expect().
+		Status(http.StatusOK)
+
+	e.POST("/logout").
+		WithHeader("Authorization", "Bearer "+first).
+		Expect().
+		Status(http.StatusUnauthorized)
+
+	// Logout-all revokes every remaining token of the user.
+	e.POST("/logout-all").
+		WithHeader("Authorization", "Bearer "+second).
+		Expect().
+		Status(http.StatusOK)
+
+	e.POST("/logout").
+		WithHeader("Authorization", "Bearer "+third).
+		Expect().
+		Status(http.StatusUnauthorized)
+}
+
+func TestURLShortener_Expiry(t *testing.T) {
+	u := url.URL{
+		Scheme: "http",
+		Host:   host,
+	}
+	e := httpexpect.Default(t, u.String())
+
+	token := authenticate(e)
+	alias := random.String(10)
+
+	e.POST("/save").
+		WithHeader("Authorization", "Bearer "+token).
+		WithJSON(save.Request{
+			URL:   gofakeit.URL(),
+			Alias: alias,
+			TTL:   "1s",
+		}).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().
+		ContainsKey("expires_at")
+
+	e.GET("/" + alias).
+		WithRedirectPolicy(httpexpect.DontFollowRedirects).
+		Expect().
+		Status(http.StatusFound)
+
+	time.Sleep(1100 * time.Millisecond)
+
+	e.GET("/" + alias).
+		WithRedirectPolicy(httpexpect.DontFollowRedirects).
+		Expect().
+		Status(http.StatusGone)
+}
+
+func TestURLShortener_ClickLimit(t *testing.T) {
+	u := url.URL{
+		Scheme: "http",
+		Host:   host,
+	}
+	e := httpexpect.Default(t, u.String())
+
+	token := authenticate(e)
+	alias := random.String(10)
+
+	e.POST("/save").
+		WithHeader("Authorization", "Bearer "+token).
+		WithJSON(save.Request{
+			URL:       gofakeit.URL(),
+			Alias:     alias,
+			MaxClicks: 2,
+		}).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().
+		HasValue("max_clicks", 2)
+
+	for i := 0; i < 2; i++ {
+		e.GET("/" + alias).
+			WithRedirectPolicy(httpexpect.DontFollowRedirects).
+			Expect().
+			Status(http.StatusFound)
+	}
+
+	e.GET("/" + alias).
+		WithRedirectPolicy(httpexpect.DontFollowRedirects).
+		Expect().
+		Status(http.StatusGone)
+}
+
+func TestURLShortener_Stats(t *testing.T) {
+	u := url.URL{
+		Scheme: "http",
+		Host:   host,
+	}
+	e := httpexpect.Default(t, u.String())
+
+	owner := authenticate(e)
+	stranger := authenticate(e)
+
+	alias := random.String(10)
+
+	e.POST("/save").
+		WithHeader("Authorization", "Bearer "+owner).
+		WithJSON(save.Request{
+			URL:   gofakeit.URL(),
+			Alias: alias,
+		}).
+		Expect().
+		Status(http.StatusOK)
+
+	for i := 0; i < 3; i++ {
+		e.GET("/"+alias).
+			WithHeader("Referer", "https://referrer.example").
+			WithRedirectPolicy(httpexpect.DontFollowRedirects).
+			Expect().
+			Status(http.StatusFound)
+	}
+
+	// Clicks are saved in the background.
+	require.Eventually(t, func() bool {
+		req, err := http.NewRequest(http.MethodGet, u.String()+"/"+alias+"/stats", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+owner)
+
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		var resp stats.Response
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&resp))
+
+		return resp.TotalClicks == 3
+	}, 2*time.Second, 50*time.Millisecond)
+
+	obj := e.GET("/"+alias+"/stats").
+		WithHeader("Authorization", "Bearer "+owner).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+
+	obj.HasValue("unique_visitors", 1)
+	obj.Value("daily").Array().Length().IsEqual(1)
+
+	e.GET("/"+alias+"/stats").
+		WithHeader("Authorization", "Bearer "+stranger).
+		Expect().
+		Status(http.StatusForbidden)
+}
+
+func TestURLShortener_Probes(t *testing.T) {
+	u := url.URL{
+		Scheme: "http",
+		Host:   host,
+	}
+	e := httpexpect.Default(t, u.String())
+
+	e.GET("/healthz").
+		Expect().
+		Status(http.StatusOK)
+
+	e.GET("/readyz").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().
+		Value("migration_version").Number().Gt(0)
+}
+
+func TestURLShortener_Metrics(t *testing.T) {
+	u := url.URL{
+		Scheme: "http",
+		Host:   host,
+	}
+	e := httpexpect.Default(t, u.String())
+
+	e.GET("/" + random.String(10)).
+		Expect().
+		Status(http.StatusNotFound)
+
+	admin := httpexpect.Default(t, "http://"+adminHost)
+
+	body := admin.GET("/metrics").
+		Expect().
+		Status(http.StatusOK).
+		Body()
+
+	body.Contains(`url_shortener_http_requests_total{method="GET",route="/{alias}",status="404"}`)
+	body.Contains("url_shortener_redirects_not_found_total")
+	body.Contains(`url_shortener_storage_operation_duration_seconds_count{method="HitURL"}`)
+}